@@ -0,0 +1,87 @@
+// Package chainlink provides helpers for reading Chainlink price feeds.
+package chainlink
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+)
+
+// Methods of Chainlink aggregator price feeds.
+var (
+	DecimalsMethod        = abi.MustParseMethod(`function decimals() external view returns (uint8)`)
+	DescriptionMethod     = abi.MustParseMethod(`function description() external view returns (string)`)
+	LatestRoundDataMethod = abi.MustParseMethod(`
+		function latestRoundData() external view returns (
+			uint80 roundId,
+			int256 answer,
+			uint256 startedAt,
+			uint256 updatedAt,
+			uint80 answeredInRound
+		)
+	`)
+)
+
+var (
+	// ErrInvalidAnswer is returned when the feed answer is not a positive
+	// price.
+	ErrInvalidAnswer = errors.New("chainlink: answer is not positive")
+
+	// ErrIncompleteRound is returned when the latest round has not been
+	// updated yet.
+	ErrIncompleteRound = errors.New("chainlink: round is not complete")
+)
+
+// StaleRoundError is returned when the latest round of a feed is older than
+// the allowed age.
+type StaleRoundError struct {
+	UpdatedAt time.Time
+	Age       time.Duration
+	MaxAge    time.Duration
+}
+
+// Error implements the error interface.
+func (e *StaleRoundError) Error() string {
+	return fmt.Sprintf("chainlink: round updated at %s is %s old, which exceeds %s", e.UpdatedAt.UTC().Format(time.RFC3339), e.Age, e.MaxAge)
+}
+
+// RoundData is the result of the latestRoundData method.
+type RoundData struct {
+	RoundID         *big.Int
+	Answer          *big.Int
+	StartedAt       *big.Int
+	UpdatedAt       *big.Int
+	AnsweredInRound *big.Int
+}
+
+// Results returns pointers to the round fields in the order they are
+// returned by latestRoundData, to be used as multicall results.
+func (r *RoundData) Results() []any {
+	return []any{&r.RoundID, &r.Answer, &r.StartedAt, &r.UpdatedAt, &r.AnsweredInRound}
+}
+
+// Validate checks that the round holds a positive answer that was updated
+// at most maxAge before now.
+func (r *RoundData) Validate(now time.Time, maxAge time.Duration) error {
+	if r.Answer.Sign() <= 0 {
+		return ErrInvalidAnswer
+	}
+	if r.UpdatedAt.Sign() == 0 || r.AnsweredInRound.Cmp(r.RoundID) < 0 {
+		return ErrIncompleteRound
+	}
+	updatedAt := time.Unix(r.UpdatedAt.Int64(), 0)
+	if age := now.Sub(updatedAt); age > maxAge {
+		return &StaleRoundError{UpdatedAt: updatedAt, Age: age, MaxAge: maxAge}
+	}
+	return nil
+}
+
+// Price returns the answer of the round as a decimal number, using the
+// decimals of the feed.
+func (r *RoundData) Price(decimals uint8) *big.Rat {
+	den := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Rat).SetFrac(r.Answer, den)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"workshop/erc20"
+)
+
+func runAllowance(ctx context.Context, args []string) error {
+	var (
+		opts    options
+		token   addressFlag
+		spender = addressFlag{addr: SwapContract, set: true}
+	)
+	fs := flag.NewFlagSet("allowance", flag.ContinueOnError)
+	opts.register(fs)
+	fs.Var(&token, "token", "token address")
+	fs.Var(&spender, "spender", "spender address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token": &token}); err != nil {
+		return err
+	}
+
+	s, err := opts.newSession(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	erc20Token := erc20.New(s.client, token.addr).At(s.block)
+	decimals, err := erc20Token.Decimals(ctx)
+	if err != nil {
+		return err
+	}
+	allowance, err := erc20Token.Allowance(ctx, s.account, spender.addr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Allowance: %s\n", formatAmount(allowance, decimals))
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/erc20"
+	"workshop/txutil"
+)
+
+func runApprove(ctx context.Context, args []string) error {
+	var (
+		opts    options
+		txOpts  txOptions
+		token   addressFlag
+		spender = addressFlag{addr: SwapContract, set: true}
+		amount  string
+	)
+	fs := flag.NewFlagSet("approve", flag.ContinueOnError)
+	opts.register(fs)
+	txOpts.register(fs)
+	fs.Var(&token, "token", "token address")
+	fs.Var(&spender, "spender", "spender address")
+	fs.StringVar(&amount, "amount", "", "amount in token units (defaults to the account balance)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token": &token}); err != nil {
+		return err
+	}
+
+	s, err := opts.newSession(ctx, !txOpts.dryRun)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, token.addr)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, token.addr); err != nil {
+		return err
+	}
+	value := tokens[token.addr].Balance
+	if amount != "" {
+		if value, err = parseAmount(amount, tokens[token.addr].Decimals); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.approve(ctx, txOpts, token.addr, spender.addr, tokens[token.addr], value)
+	return err
+}
+
+// approve ensures that the spender is allowed to spend at least the given
+// amount of tokens. The approval transaction is sent only if the allowance
+// at the session block is too low.
+//
+// In dry-run mode, the approval transaction is only simulated. The returned
+// value is false if the allowance is still too low after the call.
+func (s *session) approve(ctx context.Context, txOpts txOptions, tokenAddr, spenderAddr types.Address, token Token, amount *big.Int) (bool, error) {
+	erc20Token := erc20.New(s.client, tokenAddr).At(s.block)
+	allowance, err := erc20Token.Allowance(ctx, s.account, spenderAddr)
+	if err != nil {
+		return false, err
+	}
+	if allowance.Cmp(amount) >= 0 {
+		fmt.Printf("Token approval complete!\n")
+		return true, nil
+	}
+
+	if err := s.verifySpender(ctx, txOpts, spenderAddr); err != nil {
+		return false, err
+	}
+
+	fmt.Printf("Approving %s\n", token.FormatAmount(amount))
+	if txOpts.dryRun {
+		tx, err := erc20Token.ApproveTx(spenderAddr, amount)
+		if err != nil {
+			return false, err
+		}
+		sim, err := txutil.Simulate(ctx, s.client, *tx, s.account, s.block)
+		if err != nil {
+			return false, err
+		}
+		printSimulation("Approve", sim)
+		return false, nil
+	}
+
+	hash, err := erc20Token.Approve(ctx, spenderAddr, amount)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("Approve TX hash: %s\n", hash.String())
+	fmt.Printf("Waiting for approval to be mined...\n")
+	if _, err := txutil.WaitForReceipt(ctx, s.client, *hash, txOpts.waitOptions()); err != nil {
+		return false, err
+	}
+
+	fmt.Printf("Token approval complete!\n")
+	return true, nil
+}
+
+// trustedCodeHashes maps the keccak256 hashes of the runtime code of
+// contracts that may be approved to spend tokens to their names: the swap
+// wrapper, the SwapRouter02 and PancakeSwap SmartRouter contracts and the
+// UniswapV2Router02 contract. The routers embed chain specific addresses in
+// their code, so each deployment has its own hash. The hash of a refused
+// spender is printed, so it can be reviewed and pinned here or passed with
+// -trust-code-hash.
+var trustedCodeHashes = map[types.Hash]string{}
+
+// verifySpender checks that the spender is a contract whose runtime code
+// hash is pinned in trustedCodeHashes or given with -trust-code-hash. The
+// hash is checked for every spender, including the contracts of the known
+// deployments. An address without code or with unknown code is refused,
+// unless -allow-unknown-spender is set. In dry-run mode, nothing is approved,
+// so unknown spenders are only reported.
+func (s *session) verifySpender(ctx context.Context, txOpts txOptions, spenderAddr types.Address) error {
+	code, err := s.client.GetCode(ctx, spenderAddr, s.block)
+	if err != nil {
+		return err
+	}
+	allowUnknown := txOpts.allowUnknownSpender || txOpts.dryRun
+	if len(code) == 0 {
+		if allowUnknown {
+			fmt.Printf("Warning: spender %s is not a contract\n", spenderAddr)
+			return nil
+		}
+		return fmt.Errorf("spender %s is not a contract, use -allow-unknown-spender to approve it anyway", spenderAddr)
+	}
+	codeHash := crypto.Keccak256(code)
+	if name, ok := trustedCodeHashes[codeHash]; ok {
+		fmt.Printf("Spender code verified: %s\n", name)
+		return nil
+	}
+	for _, hash := range txOpts.trustedCodeHashes {
+		if hash == codeHash {
+			fmt.Printf("Spender code verified: %s\n", codeHash)
+			return nil
+		}
+	}
+	if allowUnknown {
+		fmt.Printf("Warning: spender %s runs unknown code with hash %s\n", spenderAddr, codeHash)
+		return nil
+	}
+	return fmt.Errorf(
+		"spender %s runs unknown code with hash %s, use -trust-code-hash %s to trust it",
+		spenderAddr, codeHash, codeHash,
+	)
+}
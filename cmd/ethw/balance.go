@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+func runBalance(ctx context.Context, args []string) error {
+	var (
+		opts   options
+		tokens addressListFlag
+	)
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	opts.register(fs)
+	fs.Var(&tokens, "token", "token address, may be repeated (prints the ETH balance if not set)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := opts.newSession(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		balance, err := s.client.GetBalance(ctx, s.account, s.block)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("ETH balance: %s\n", formatAmount(balance, 18))
+		return nil
+	}
+
+	info, err := fetchTokens(ctx, s.client, s.block, s.account, tokens...)
+	if err != nil {
+		return err
+	}
+	for _, address := range tokens {
+		token := info[address]
+		if token.Err != nil {
+			fmt.Printf("%s balance: %v\n", token.Name, token.Err)
+			continue
+		}
+		fmt.Printf("%s balance: %s\n", token.Name, token.FormatAmount(token.Balance))
+	}
+	return nil
+}
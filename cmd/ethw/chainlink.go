@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/chainlink"
+	"workshop/multicall"
+	"workshop/uniswapv3"
+)
+
+// checkChainlink compares the pool price of tokenIn expressed in tokenOut
+// with the price reported by a Chainlink feed and returns an error if they
+// differ by more than maxBps basis points, or if the feed round is older
+// than maxAge at the given block.
+//
+// If inverted is true, the feed reports the price of tokenOut expressed in
+// tokenIn.
+func checkChainlink(ctx context.Context, client rpc.RPC, block types.BlockNumber, feed types.Address, inverted bool, spot *big.Rat, maxAge time.Duration, maxBps uint64, prec int) error {
+	var (
+		decimals    uint8
+		description string
+		round       chainlink.RoundData
+	)
+	calls := []*multicall.Call{
+		{Target: feed, Method: chainlink.DecimalsMethod, Results: []any{&decimals}},
+		{Target: feed, Method: chainlink.DescriptionMethod, Results: []any{&description}},
+		{Target: feed, Method: chainlink.LatestRoundDataMethod, Results: round.Results()},
+	}
+	if err := aggregate(ctx, client, block, calls); err != nil {
+		return err
+	}
+	b, err := client.BlockByNumber(ctx, block, false)
+	if err != nil {
+		return err
+	}
+	if err := round.Validate(b.Timestamp, maxAge); err != nil {
+		return fmt.Errorf("feed %s (%s): %w", feed, description, err)
+	}
+
+	price := round.Price(decimals)
+	if inverted {
+		price.Inv(price)
+	}
+	deviation := priceDeviationBps(spot, price)
+	fmt.Printf("Chainlink price (%s): %s\n", description, uniswapv3.FormatPrice(price, prec))
+	fmt.Printf("Chainlink price deviation: %s%%\n", new(big.Rat).Quo(deviation, big.NewRat(100, 1)).FloatString(2))
+	if deviation.Cmp(new(big.Rat).SetUint64(maxBps)) > 0 {
+		return fmt.Errorf("pool price deviates from the Chainlink price by more than %d basis points", maxBps)
+	}
+	return nil
+}
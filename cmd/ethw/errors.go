@@ -0,0 +1,15 @@
+package main
+
+import "workshop/revert"
+
+// knownErrors is used to decode custom errors returned by the contracts
+// ethw interacts with.
+var knownErrors = revert.MustParseRegistry(
+	// ERC-6093 token errors, used by OpenZeppelin 5.x.
+	`error ERC20InsufficientBalance(address sender, uint256 balance, uint256 needed)`,
+	`error ERC20InvalidSender(address sender)`,
+	`error ERC20InvalidReceiver(address receiver)`,
+	`error ERC20InsufficientAllowance(address spender, uint256 allowance, uint256 needed)`,
+	`error ERC20InvalidApprover(address approver)`,
+	`error ERC20InvalidSpender(address spender)`,
+)
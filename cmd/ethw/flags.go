@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/txmodifier"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+
+	"workshop/txutil"
+	"workshop/uniswapv3"
+)
+
+// keyEnv is the environment variable used when the -key flag is not set.
+const keyEnv = "ETHW_PRIVATE_KEY"
+
+// options are the flags shared by all commands.
+type options struct {
+	rpcURL  string
+	chainID uint64
+	key     string
+	account addressFlag
+	block   string
+}
+
+// register adds the shared flags to the flag set.
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.rpcURL, "rpc", "https://rpc.ankr.com/eth_goerli", "JSON-RPC endpoint URL")
+	fs.Uint64Var(&o.chainID, "chain-id", 5, "chain ID used to sign transactions and to select contract deployments")
+	fs.StringVar(&o.key, "key", "", "hex encoded private key (defaults to $"+keyEnv+")")
+	fs.Var(&o.account, "account", "account address (defaults to the address of the private key)")
+	fs.StringVar(&o.block, "block", "latest", `block to read the state at: a block number, "latest", "safe" or "finalized"`)
+}
+
+// txOptions are the flags shared by commands that send transactions.
+type txOptions struct {
+	confirmations       uint64
+	timeout             time.Duration
+	dryRun              bool
+	trustedCodeHashes   hashListFlag
+	allowUnknownSpender bool
+}
+
+// register adds the transaction flags to the flag set.
+func (o *txOptions) register(fs *flag.FlagSet) {
+	fs.Uint64Var(&o.confirmations, "confirmations", 1, "number of confirmations to wait for")
+	fs.DurationVar(&o.timeout, "timeout", 5*time.Minute, "maximum time to wait for a transaction to be confirmed")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "simulate transactions without signing or sending them")
+	fs.Var(&o.trustedCodeHashes, "trust-code-hash", "runtime code hash of an additional contract that may be approved to spend tokens (may be repeated)")
+	fs.BoolVar(&o.allowUnknownSpender, "allow-unknown-spender", false, "approve spenders that are not contracts or run unknown code")
+}
+
+// waitOptions returns the options for txutil.WaitForReceipt.
+func (o *txOptions) waitOptions() txutil.WaitOptions {
+	return txutil.WaitOptions{Confirmations: o.confirmations, Timeout: o.timeout}
+}
+
+// poolOptions are the flags shared by commands that use Uniswap V3 pools.
+type poolOptions struct {
+	dex string
+	fee uint
+}
+
+// register adds the pool flags to the flag set.
+func (o *poolOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.dex, "dex", "uniswap", "Uniswap V3 deployment to use: uniswap, sushiswap or pancakeswap")
+	fs.UintVar(&o.fee, "fee", 0, "pool fee tier in hundredths of a bip (defaults to the deepest pool)")
+}
+
+// deployer returns the deployment selected by the -dex flag on the chain.
+func (o *poolOptions) deployer(chainID uint64) (uniswapv3.PoolDeployer, error) {
+	d, ok := uniswapv3.FindDeployer(chainID, o.dex)
+	if !ok {
+		return uniswapv3.PoolDeployer{}, fmt.Errorf("%s is not deployed on chain %d", o.dex, chainID)
+	}
+	return d, nil
+}
+
+// session holds the RPC client and the account a command operates on.
+type session struct {
+	client  rpc.RPC
+	key     *wallet.PrivateKey
+	account types.Address
+
+	// block is the block number all reads are pinned to. It is resolved once
+	// when the session is created, so every call within a command observes
+	// the same state.
+	block types.BlockNumber
+}
+
+// newSession creates a JSON-RPC client from the shared flags.
+//
+// If requireKey is true, a private key must be provided, so the client is
+// able to sign transactions. Sending transactions requires reading the
+// latest state, so historical blocks are rejected in that case.
+func (o *options) newSession(ctx context.Context, requireKey bool) (*session, error) {
+	var key *wallet.PrivateKey
+	keyHex := o.key
+	if keyHex == "" {
+		keyHex = os.Getenv(keyEnv)
+	}
+	if keyHex != "" {
+		keyBytes, err := hexutil.HexToBytes(keyHex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		key = wallet.NewKeyFromBytes(keyBytes)
+	}
+	if requireKey && key == nil {
+		return nil, fmt.Errorf("private key is required, use -key or $%s", keyEnv)
+	}
+	if requireKey && o.block != "latest" {
+		return nil, errors.New("-block cannot be used when sending transactions")
+	}
+
+	// Create a JSON-RPC transport.
+	rpcTransport, err := transport.NewHTTP(transport.HTTPOptions{
+		URL: o.rpcURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a JSON-RPC client.
+	clientOpts := []rpc.ClientOptions{rpc.WithTransport(rpcTransport)}
+	if key != nil {
+		clientOpts = append(
+			clientOpts,
+			rpc.WithKeys(key),
+			rpc.WithDefaultAddress(key.Address()),
+			rpc.WithChainID(o.chainID),
+			rpc.WithTXModifiers(
+				txmodifier.NewNonceProvider(false),
+				txmodifier.NewGasLimitEstimator(1.25, 0, 0),
+				txmodifier.NewEIP1559GasFeeEstimator(1.5, 1.25, nil, nil, nil, nil),
+			),
+		)
+	}
+	client, err := rpc.NewClient(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := resolveBlock(ctx, rpcTransport, o.block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &session{client: client, key: key, block: block}
+	switch {
+	case o.account.set:
+		s.account = o.account.addr
+	case key != nil:
+		s.account = key.Address()
+	default:
+		return nil, errors.New("account is required, use -account or -key")
+	}
+	if key != nil && o.account.set && o.account.addr != key.Address() {
+		return nil, errors.New("account does not match the private key")
+	}
+	return s, nil
+}
+
+// resolveBlock converts the -block flag value to a block number.
+//
+// Block tags are resolved to the number of the block they point to at the
+// time of the call.
+func resolveBlock(ctx context.Context, t transport.Transport, block string) (types.BlockNumber, error) {
+	switch block {
+	case "latest", "safe", "finalized":
+		// The go-eth BlockNumber type does not support the "safe" and
+		// "finalized" tags, so the call is made using the transport directly.
+		var res struct {
+			Number types.Number `json:"number"`
+		}
+		if err := t.Call(ctx, &res, "eth_getBlockByNumber", block, false); err != nil {
+			return types.BlockNumber{}, fmt.Errorf("unable to resolve %q block: %w", block, err)
+		}
+		return types.BlockNumberFromBigInt(res.Number.Big()), nil
+	default:
+		n, ok := new(big.Int).SetString(block, 0)
+		if !ok || n.Sign() < 0 {
+			return types.BlockNumber{}, fmt.Errorf("invalid block: %q", block)
+		}
+		return types.BlockNumberFromBigInt(n), nil
+	}
+}
+
+// addressFlag is a flag.Value for a single address.
+type addressFlag struct {
+	addr types.Address
+	set  bool
+}
+
+func (f *addressFlag) String() string {
+	if !f.set {
+		return ""
+	}
+	return f.addr.String()
+}
+
+func (f *addressFlag) Set(s string) error {
+	addr, err := types.AddressFromHex(s)
+	if err != nil {
+		return err
+	}
+	f.addr, f.set = addr, true
+	return nil
+}
+
+// addressListFlag is a flag.Value for a list of addresses. The flag may be
+// repeated or given a comma separated list.
+type addressListFlag []types.Address
+
+func (f *addressListFlag) String() string {
+	var s []string
+	for _, addr := range *f {
+		s = append(s, addr.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *addressListFlag) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		addr, err := types.AddressFromHex(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		*f = append(*f, addr)
+	}
+	return nil
+}
+
+// chainlinkFeed is a Chainlink feed that reports the price of the Base token
+// expressed in the Quote token.
+type chainlinkFeed struct {
+	Base, Quote types.Address
+	Feed        types.Address
+}
+
+// feedListFlag is a flag.Value for a list of Chainlink feeds. Each feed is
+// given as "base:quote:feed" addresses. The flag may be repeated.
+type feedListFlag []chainlinkFeed
+
+func (f *feedListFlag) String() string {
+	var s []string
+	for _, feed := range *f {
+		s = append(s, feed.Base.String()+":"+feed.Quote.String()+":"+feed.Feed.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *feedListFlag) Set(s string) error {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid feed %q, expected base:quote:feed addresses", s)
+	}
+	var addrs [3]types.Address
+	for i, part := range parts {
+		addr, err := types.AddressFromHex(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		addrs[i] = addr
+	}
+	*f = append(*f, chainlinkFeed{Base: addrs[0], Quote: addrs[1], Feed: addrs[2]})
+	return nil
+}
+
+// find returns the feed for the token pair. The inverted flag is true if
+// the feed reports the price of tokenOut expressed in tokenIn.
+func (f feedListFlag) find(tokenIn, tokenOut types.Address) (feed types.Address, inverted bool, ok bool) {
+	for _, c := range f {
+		switch {
+		case c.Base == tokenIn && c.Quote == tokenOut:
+			return c.Feed, false, true
+		case c.Base == tokenOut && c.Quote == tokenIn:
+			return c.Feed, true, true
+		}
+	}
+	return types.Address{}, false, false
+}
+
+// hashListFlag is a flag.Value for a list of hashes. The flag may be
+// repeated or given a comma separated list.
+type hashListFlag []types.Hash
+
+func (f *hashListFlag) String() string {
+	var s []string
+	for _, hash := range *f {
+		s = append(s, hash.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *hashListFlag) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		hash, err := types.HashFromHex(strings.TrimSpace(part), types.PadNone)
+		if err != nil {
+			return err
+		}
+		*f = append(*f, hash)
+	}
+	return nil
+}
+
+// requireFlags returns an error if any of the given address flags is not set.
+func requireFlags(flags map[string]*addressFlag) error {
+	for name, f := range flags {
+		if !f.set {
+			return fmt.Errorf("-%s is required", name)
+		}
+	}
+	return nil
+}
+
+// parseAmount converts a decimal amount in token units, like "1.5", to
+// the smallest token unit using the token decimals.
+func parseAmount(s string, decimals uint8) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount: %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(pow10(decimals)))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("amount %q has more than %d decimal places", s, decimals)
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// formatAmount converts an amount in the smallest token unit to a decimal
+// string using the token decimals.
+func formatAmount(x *big.Int, decimals uint8) string {
+	if x == nil {
+		return "0"
+	}
+	return new(big.Rat).SetFrac(x, pow10(decimals)).FloatString(int(decimals))
+}
+
+// pow10 returns 10^n.
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
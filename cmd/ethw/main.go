@@ -0,0 +1,76 @@
+// Command ethw interacts with ERC20 tokens and Uniswap V2 and V3 pools.
+//
+// Usage:
+//
+//	ethw <command> [flags]
+//
+// Run "ethw <command> -h" to see the flags accepted by a command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// command is a single ethw subcommand.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "balance", usage: "print the ETH or token balance of an account", run: runBalance},
+	{name: "token-info", usage: "print the name, decimals and balance of tokens", run: runTokenInfo},
+	{name: "allowance", usage: "print the allowance granted to a spender", run: runAllowance},
+	{name: "approve", usage: "approve a spender to use tokens", run: runApprove},
+	{name: "price", usage: "print the current price of a Uniswap V3 pool", run: runPrice},
+	{name: "quote", usage: "quote a Uniswap V3 swap using the QuoterV2 contract", run: runQuote},
+	{name: "twap", usage: "print the time-weighted average price of a Uniswap V3 pool", run: runTWAP},
+	{name: "swap", usage: "swap tokens through a Uniswap V3 pool", run: runSwap},
+	{name: "swap-route", usage: "swap tokens through the best multi-hop route of Uniswap V3 pools", run: runSwapRoute},
+	{name: "price-v2", usage: "print the current price of a Uniswap V2 pair", run: runPriceV2},
+	{name: "swap-v2", usage: "swap tokens through a Uniswap V2 pair", run: runSwapV2},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer ctxCancel()
+
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(ctx, os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(2)
+			}
+			fmt.Fprintf(os.Stderr, "ethw %s: %v\n", name, knownErrors.Wrap(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if name != "-h" && name != "-help" && name != "help" {
+		fmt.Fprintf(os.Stderr, "ethw: unknown command %q\n\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: ethw <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.usage)
+	}
+}
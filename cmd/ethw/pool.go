@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/multicall"
+	"workshop/uniswapv3"
+)
+
+// Pool is the state of an Uniswap V3 pool for a token pair.
+type Pool struct {
+	Address     types.Address
+	Fee         uint32
+	TickSpacing int
+	Liquidity   *big.Int
+	Slot0       UniswapSlot0
+
+	// Inverted is true if the first token of the pair is the token1 of the
+	// pool.
+	Inverted bool
+
+	// state caches the tick data loaded by simulateSwap, so repeated
+	// simulations through the pool read it only once.
+	state *uniswapv3.PoolState
+}
+
+// printPool prints the address, fee tier and liquidity of a pool.
+func printPool(p *Pool) {
+	fmt.Printf("Pool address: %s\n", p.Address.String())
+	fmt.Printf("Fee tier: %d\n", p.Fee)
+	fmt.Printf("Tick spacing: %d\n", p.TickSpacing)
+	fmt.Printf("Liquidity: %s\n", p.Liquidity.String())
+}
+
+// PoolNotFoundError is returned by findPool when the factory has no pool for
+// the token pair.
+type PoolNotFoundError struct {
+	Token0, Token1 types.Address
+	Fee            uint32 // Fee is zero if all fee tiers were checked.
+}
+
+// Error implements the error interface.
+func (e *PoolNotFoundError) Error() string {
+	if e.Fee == 0 {
+		return fmt.Sprintf("no pool exists for tokens %s and %s", e.Token0, e.Token1)
+	}
+	return fmt.Sprintf("no pool with the %d fee tier exists for tokens %s and %s", e.Fee, e.Token0, e.Token1)
+}
+
+// PoolNotInitializedError is returned by findPool when the pool exists, but
+// its initial price has not been set yet, so it cannot be traded.
+type PoolNotInitializedError struct {
+	Address types.Address
+	Fee     uint32
+}
+
+// Error implements the error interface.
+func (e *PoolNotInitializedError) Error() string {
+	return fmt.Sprintf("pool %s with the %d fee tier is not initialized", e.Address, e.Fee)
+}
+
+// findPool returns the pool of the token pair with the given fee tier in the
+// deployment. If fee is zero, all fee tiers are checked and the initialized
+// pool with the highest in-range liquidity is returned.
+func findPool(ctx context.Context, client rpc.RPC, block types.BlockNumber, deployer uniswapv3.PoolDeployer, tokenA, tokenB types.Address, fee uint32) (*Pool, error) {
+	pools, err := findPools(ctx, client, block, deployer, tokenA, tokenB, fee)
+	if err != nil {
+		return nil, err
+	}
+	best := pools[0]
+	for _, p := range pools[1:] {
+		if p.Liquidity.Cmp(best.Liquidity) > 0 {
+			best = p
+		}
+	}
+	return best, nil
+}
+
+// findPools returns the initialized pools of the token pair in the
+// deployment. If fee is zero, pools of all fee tiers are returned, otherwise
+// only the pool with the given fee tier.
+//
+// Pool addresses are computed locally and cross-checked against the
+// factory, so a wrong factory or init code hash is detected instead of
+// reading an unrelated address.
+func findPools(ctx context.Context, client rpc.RPC, block types.BlockNumber, deployer uniswapv3.PoolDeployer, tokenA, tokenB types.Address, fee uint32) ([]*Pool, error) {
+	tiers := deployer.FeeTiers
+	if fee != 0 {
+		if !deployer.SupportsFee(fee) {
+			return nil, fmt.Errorf("%s does not support the %d fee tier", deployer.Name, fee)
+		}
+		tiers = []uint32{fee}
+	}
+	token0, token1, inverted := uniswapv3.SortTokens(tokenA, tokenB)
+
+	// Ask the factory which pools exist.
+	factoryPools := make([]types.Address, len(tiers))
+	var calls []*multicall.Call
+	for i, tier := range tiers {
+		calls = append(calls, &multicall.Call{
+			Target:  deployer.Factory,
+			Method:  uniswapGetPool,
+			Args:    []any{token0, token1, tier},
+			Results: []any{&factoryPools[i]},
+		})
+	}
+	if err := aggregate(ctx, client, block, calls); err != nil {
+		return nil, err
+	}
+	var pools []*Pool
+	for i, tier := range tiers {
+		if factoryPools[i] == (types.Address{}) {
+			continue
+		}
+		poolAddr := deployer.PoolAddress(tokenA, tokenB, tier)
+		if factoryPools[i] != poolAddr {
+			return nil, fmt.Errorf(
+				"factory returned pool %s for the %d fee tier, but %s was computed",
+				factoryPools[i], tier, poolAddr,
+			)
+		}
+		code, err := client.GetCode(ctx, poolAddr, block)
+		if err != nil {
+			return nil, err
+		}
+		if len(code) == 0 {
+			continue
+		}
+		pools = append(pools, &Pool{Address: poolAddr, Fee: tier, Inverted: inverted})
+	}
+	if len(pools) == 0 {
+		return nil, &PoolNotFoundError{Token0: token0, Token1: token1, Fee: fee}
+	}
+
+	// Read the state of all pools in a single call.
+	var (
+		poolFees     = make([]uint32, len(pools))
+		tickSpacings = make([]int32, len(pools))
+	)
+	calls = nil
+	for i, p := range pools {
+		calls = append(
+			calls,
+			&multicall.Call{Target: p.Address, Method: uniswapFee, Results: []any{&poolFees[i]}},
+			&multicall.Call{Target: p.Address, Method: uniswapTickSpacing, Results: []any{&tickSpacings[i]}},
+			&multicall.Call{Target: p.Address, Method: uniswapLiquidity, Results: []any{&p.Liquidity}},
+			&multicall.Call{Target: p.Address, Method: uniswapSlot0, Results: []any{
+				&p.Slot0.SqrtPriceX96,
+				&p.Slot0.Tick,
+				&p.Slot0.ObservationIndex,
+				&p.Slot0.ObservationCardinality,
+				&p.Slot0.ObservationCardinalityNext,
+				&p.Slot0.FeeProtocol,
+				&p.Slot0.Unlocked,
+			}},
+		)
+	}
+	if err := aggregate(ctx, client, block, calls); err != nil {
+		return nil, err
+	}
+	for i, p := range pools {
+		if poolFees[i] != p.Fee {
+			return nil, fmt.Errorf("pool %s reports the %d fee tier, but %d was expected", p.Address, poolFees[i], p.Fee)
+		}
+		if tickSpacings[i] <= 0 {
+			return nil, fmt.Errorf("pool %s reports an invalid tick spacing: %d", p.Address, tickSpacings[i])
+		}
+		p.TickSpacing = int(tickSpacings[i])
+	}
+
+	// Skip pools whose initial price has not been set.
+	var initialized []*Pool
+	for _, p := range pools {
+		if p.Slot0.SqrtPriceX96.Sign() != 0 {
+			initialized = append(initialized, p)
+		}
+	}
+	if len(initialized) == 0 {
+		return nil, &PoolNotInitializedError{Address: pools[0].Address, Fee: pools[0].Fee}
+	}
+	return initialized, nil
+}
+
+// aggregate executes the calls using multicall.Aggregate and returns the
+// first error of a failed call.
+func aggregate(ctx context.Context, client rpc.RPC, block types.BlockNumber, calls []*multicall.Call) error {
+	if err := multicall.Aggregate(ctx, client, block, calls); err != nil {
+		return err
+	}
+	for _, call := range calls {
+		if call.Err != nil {
+			return call.Err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+
+	"workshop/uniswapv3"
+)
+
+func runPrice(ctx context.Context, args []string) error {
+	var (
+		opts        options
+		poolOpts    poolOptions
+		tokenIn     addressFlag
+		tokenOut    addressFlag
+		prec        int
+		amountInStr string
+	)
+	fs := flag.NewFlagSet("price", flag.ContinueOnError)
+	opts.register(fs)
+	poolOpts.register(fs)
+	fs.Var(&tokenIn, "token-in", "address of the token to sell")
+	fs.Var(&tokenOut, "token-out", "address of the token to buy")
+	fs.IntVar(&prec, "precision", 8, "number of decimal places of printed prices")
+	fs.StringVar(&amountInStr, "amount-in", "", "amount of tokens to sell to quote the expected output for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token-in": &tokenIn, "token-out": &tokenOut}); err != nil {
+		return err
+	}
+
+	deployer, err := poolOpts.deployer(opts.chainID)
+	if err != nil {
+		return err
+	}
+
+	s, err := opts.newSession(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, tokenIn.addr, tokenOut.addr); err != nil {
+		return err
+	}
+	in, out := tokens[tokenIn.addr], tokens[tokenOut.addr]
+
+	// Find the pool and read its current state.
+	pool, err := findPool(ctx, s.client, s.block, deployer, tokenIn.addr, tokenOut.addr, uint32(poolOpts.fee))
+	if err != nil {
+		return err
+	}
+	printPool(pool)
+
+	fmt.Printf("Current price: %s\n", uniswapv3.FormatPrice(poolPrice(pool.Slot0, pool.Inverted, in, out), prec))
+	fmt.Printf("Current tick: %d\n", pool.Slot0.Tick)
+	if amountInStr == "" {
+		return nil
+	}
+
+	// Quote the swap using the in-range liquidity of the pool.
+	amountIn, err := parseAmount(amountInStr, in.Decimals)
+	if err != nil {
+		return err
+	}
+	quote, err := uniswapv3.QuoteExactInput(
+		pool.Slot0.SqrtPriceX96,
+		int(pool.Slot0.Tick),
+		pool.TickSpacing,
+		pool.Liquidity,
+		amountIn,
+		pool.Fee,
+		!pool.Inverted,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to quote the swap: %w", err)
+	}
+	printQuote(quote, pool.Inverted, in, out, prec)
+	if !quote.CrossesTick {
+		return nil
+	}
+
+	// Simulate the swap through all initialized ticks it crosses.
+	zeroForOne := !pool.Inverted
+	sqrtPriceLimitX96 := new(big.Int).Sub(uniswapv3.MaxSqrtRatio, big.NewInt(1))
+	if zeroForOne {
+		sqrtPriceLimitX96 = new(big.Int).Add(uniswapv3.MinSqrtRatio, big.NewInt(1))
+	}
+	res, err := simulateSwap(ctx, s.client, s.block, pool, zeroForOne, amountIn, sqrtPriceLimitX96)
+	if errors.Is(err, uniswapv3.ErrInsufficientLiquidity) {
+		fmt.Printf("Warning: the pool does not have enough liquidity to swap the whole amount\n")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to simulate the swap: %w", err)
+	}
+	printSwapResult(res, pool.Inverted, in, out, prec)
+	if res.AmountIn.Cmp(amountIn) < 0 {
+		fmt.Printf("Warning: the pool does not have enough liquidity to swap the whole amount\n")
+	}
+	return nil
+}
+
+// printQuote prints the expected amounts, fee and price impact of a swap.
+func printQuote(q *uniswapv3.Quote, inverted bool, tokenIn, tokenOut Token, prec int) {
+	impact := q.PriceImpact()
+	impact.Mul(impact, big.NewRat(100, 1))
+	priceAfter := poolPrice(UniswapSlot0{SqrtPriceX96: q.SqrtPriceAfterX96}, inverted, tokenIn, tokenOut)
+	fmt.Printf("Quote:\n")
+	fmt.Printf("  Amount in:    %s\n", tokenIn.FormatAmount(new(big.Int).Add(q.AmountIn, q.FeeAmount)))
+	fmt.Printf("  Fee paid:     %s\n", tokenIn.FormatAmount(q.FeeAmount))
+	fmt.Printf("  Amount out:   %s\n", tokenOut.FormatAmount(q.AmountOut))
+	fmt.Printf("  Price impact: %s%%\n", impact.FloatString(4))
+	fmt.Printf("  Price after:  %s\n", uniswapv3.FormatPrice(priceAfter, prec))
+	if q.CrossesTick {
+		fmt.Printf("Warning: the swap crosses the current tick range, so the quote is only approximate\n")
+	}
+}
+
+// printSwapResult prints the result of a swap simulated through initialized
+// ticks.
+func printSwapResult(r *uniswapv3.SwapResult, inverted bool, tokenIn, tokenOut Token, prec int) {
+	priceAfter := poolPrice(UniswapSlot0{SqrtPriceX96: r.SqrtPriceX96}, inverted, tokenIn, tokenOut)
+	fmt.Printf("Exact simulation:\n")
+	fmt.Printf("  Amount in:     %s\n", tokenIn.FormatAmount(r.AmountIn))
+	fmt.Printf("  Fee paid:      %s\n", tokenIn.FormatAmount(r.FeeAmount))
+	fmt.Printf("  Amount out:    %s\n", tokenOut.FormatAmount(r.AmountOut))
+	fmt.Printf("  Price after:   %s\n", uniswapv3.FormatPrice(priceAfter, prec))
+	fmt.Printf("  Tick after:    %d\n", r.Tick)
+	fmt.Printf("  Ticks crossed: %d\n", r.TicksCrossed)
+}
+
+// poolPrice returns the price of tokenIn expressed in tokenOut.
+func poolPrice(slot0 UniswapSlot0, inverted bool, tokenIn, tokenOut Token) *big.Rat {
+	if inverted {
+		price := uniswapv3.SqrtPriceX96ToPrice(slot0.SqrtPriceX96, tokenOut.Decimals, tokenIn.Decimals)
+		if price.Sign() == 0 {
+			return price
+		}
+		return price.Inv(price)
+	}
+	return uniswapv3.SqrtPriceX96ToPrice(slot0.SqrtPriceX96, tokenIn.Decimals, tokenOut.Decimals)
+}
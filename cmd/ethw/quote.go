@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/multicall"
+	"workshop/uniswapv3"
+)
+
+// QuoterQuote is a quote returned by the QuoterV2 contract.
+type QuoterQuote struct {
+	// Amount is the amount of tokens received for an exact input quote or
+	// the amount of tokens sold for an exact output quote.
+	Amount                  *big.Int
+	SqrtPriceX96After       *big.Int
+	InitializedTicksCrossed uint32
+	GasEstimate             *big.Int
+}
+
+// quoteSingle quotes a swap through the pool using the QuoterV2 contract.
+// A positive amount is the exact amount of tokenIn to sell, a negative one
+// is the exact amount of tokenOut to buy.
+//
+// The quoter executes the swap and reverts it, so it is called using
+// eth_call and the result reflects the state at the given block.
+func quoteSingle(ctx context.Context, client rpc.RPC, block types.BlockNumber, quoter types.Address, pool *Pool, tokenIn, tokenOut types.Address, amount *big.Int) (*QuoterQuote, error) {
+	var (
+		q      QuoterQuote
+		method = quoterQuoteExactInputSingle
+		params = map[string]any{
+			"tokenIn":           tokenIn,
+			"tokenOut":          tokenOut,
+			"amountIn":          amount,
+			"fee":               pool.Fee,
+			"sqrtPriceLimitX96": big.NewInt(0),
+		}
+	)
+	if amount.Sign() < 0 {
+		method = quoterQuoteExactOutputSingle
+		delete(params, "amountIn")
+		params["amount"] = new(big.Int).Neg(amount)
+	}
+	call := &multicall.Call{
+		Target:  quoter,
+		Method:  method,
+		Args:    []any{params},
+		Results: []any{&q.Amount, &q.SqrtPriceX96After, &q.InitializedTicksCrossed, &q.GasEstimate},
+	}
+	if err := aggregate(ctx, client, block, []*multicall.Call{call}); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func runQuote(ctx context.Context, args []string) error {
+	var (
+		opts         options
+		poolOpts     poolOptions
+		tokenIn      addressFlag
+		tokenOut     addressFlag
+		prec         int
+		amountInStr  string
+		amountOutStr string
+	)
+	fs := flag.NewFlagSet("quote", flag.ContinueOnError)
+	opts.register(fs)
+	poolOpts.register(fs)
+	fs.Var(&tokenIn, "token-in", "address of the token to sell")
+	fs.Var(&tokenOut, "token-out", "address of the token to buy")
+	fs.IntVar(&prec, "precision", 8, "number of decimal places of printed prices")
+	fs.StringVar(&amountInStr, "amount-in", "", "exact amount of tokens to sell")
+	fs.StringVar(&amountOutStr, "amount-out", "", "exact amount of tokens to buy")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token-in": &tokenIn, "token-out": &tokenOut}); err != nil {
+		return err
+	}
+	if (amountInStr == "") == (amountOutStr == "") {
+		return errors.New("exactly one of -amount-in or -amount-out is required")
+	}
+
+	deployer, err := poolOpts.deployer(opts.chainID)
+	if err != nil {
+		return err
+	}
+	if deployer.Quoter == (types.Address{}) {
+		return fmt.Errorf("no QuoterV2 contract is known for %s on chain %d", deployer.Name, opts.chainID)
+	}
+
+	s, err := opts.newSession(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, tokenIn.addr, tokenOut.addr); err != nil {
+		return err
+	}
+	in, out := tokens[tokenIn.addr], tokens[tokenOut.addr]
+
+	// Find the pool and read its current state.
+	pool, err := findPool(ctx, s.client, s.block, deployer, tokenIn.addr, tokenOut.addr, uint32(poolOpts.fee))
+	if err != nil {
+		return err
+	}
+	printPool(pool)
+	fmt.Printf("Current price: %s\n", uniswapv3.FormatPrice(poolPrice(pool.Slot0, pool.Inverted, in, out), prec))
+	fmt.Printf("Current tick: %d\n", pool.Slot0.Tick)
+
+	// A positive amount is an exact input, a negative one an exact output.
+	var amount *big.Int
+	if amountOutStr != "" {
+		amountOut, err := parseAmount(amountOutStr, out.Decimals)
+		if err != nil {
+			return err
+		}
+		amount = new(big.Int).Neg(amountOut)
+	} else {
+		if amount, err = parseAmount(amountInStr, in.Decimals); err != nil {
+			return err
+		}
+	}
+	if amount.Sign() == 0 {
+		return errors.New("amount must be greater than zero")
+	}
+
+	// Quote the swap on chain.
+	q, err := quoteSingle(ctx, s.client, s.block, deployer.Quoter, pool, tokenIn.addr, tokenOut.addr, amount)
+	if err != nil {
+		return fmt.Errorf("QuoterV2 call failed: %w", err)
+	}
+	amountIn, amountOut := amount, q.Amount
+	if amount.Sign() < 0 {
+		amountIn, amountOut = q.Amount, new(big.Int).Neg(amount)
+	}
+	priceAfter := poolPrice(UniswapSlot0{SqrtPriceX96: q.SqrtPriceX96After}, pool.Inverted, in, out)
+	fmt.Printf("QuoterV2 quote:\n")
+	fmt.Printf("  Amount in:     %s\n", in.FormatAmount(amountIn))
+	fmt.Printf("  Amount out:    %s\n", out.FormatAmount(amountOut))
+	if amountIn.Sign() > 0 {
+		price := new(big.Rat).SetFrac(new(big.Int).Mul(amountOut, pow10(in.Decimals)), new(big.Int).Mul(amountIn, pow10(out.Decimals)))
+		fmt.Printf("  Average price: %s\n", uniswapv3.FormatPrice(price, prec))
+	}
+	fmt.Printf("  Price after:   %s\n", uniswapv3.FormatPrice(priceAfter, prec))
+	fmt.Printf("  Ticks crossed: %d\n", q.InitializedTicksCrossed)
+	fmt.Printf("  Gas estimate:  %s\n", q.GasEstimate.String())
+
+	// Cross-check the quote with the local simulation.
+	zeroForOne := !pool.Inverted
+	sqrtPriceLimitX96 := new(big.Int).Sub(uniswapv3.MaxSqrtRatio, big.NewInt(1))
+	if zeroForOne {
+		sqrtPriceLimitX96 = new(big.Int).Add(uniswapv3.MinSqrtRatio, big.NewInt(1))
+	}
+	res, err := simulateSwap(ctx, s.client, s.block, pool, zeroForOne, amount, sqrtPriceLimitX96)
+	if err != nil {
+		return fmt.Errorf("unable to simulate the swap: %w", err)
+	}
+	local, quoted, token := res.AmountOut, amountOut, out
+	if amount.Sign() < 0 {
+		local, quoted, token = res.AmountIn, amountIn, in
+	}
+	if local.Cmp(quoted) == 0 {
+		fmt.Printf("Local simulation matches the QuoterV2 quote\n")
+	} else {
+		fmt.Printf("Warning: local simulation returned %s, which differs from the QuoterV2 quote\n", token.FormatAmount(local))
+	}
+	return nil
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/uniswapv3"
+)
+
+// maxRouteHops is the maximum number of pools a route may pass through.
+const maxRouteHops = 3
+
+// defaultBaseTokens are the tokens routes may pass through when the -base
+// flag is not set, per chain: WETH, USDC and DAI.
+var defaultBaseTokens = map[uint64][]types.Address{
+	1: {
+		types.MustAddressFromHex("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"),
+		types.MustAddressFromHex("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"),
+		types.MustAddressFromHex("0x6B175474E89094C44Da98b954EedeAC495271d0F"),
+	},
+	5: {
+		types.MustAddressFromHex("0xB4FBF271143F4FBf7B91A5ded31805e42b2208d6"),
+		types.MustAddressFromHex("0x07865c6E87B9F70255377e024ace6630C1Eaa37F"),
+		types.MustAddressFromHex("0x11fE4B6AE13d2a6055C8D9cF65c55bac32B5d844"),
+	},
+}
+
+// Route is a swap path through one or more Uniswap V3 pools.
+type Route struct {
+	// Tokens are the tokens of the path, from the sold to the bought one.
+	Tokens []types.Address
+
+	// Pools are the pools of the path. Pools[i] swaps Tokens[i] for
+	// Tokens[i+1].
+	Pools []*Pool
+
+	// AmountIn is the amount of sold tokens and AmountOut is the quoted
+	// amount of bought tokens.
+	AmountIn  *big.Int
+	AmountOut *big.Int
+}
+
+// Path returns the route encoded as a path for the exactInput method of
+// the swap router.
+func (r *Route) Path() ([]byte, error) {
+	fees := make([]uint32, len(r.Pools))
+	for i, p := range r.Pools {
+		fees[i] = p.Fee
+	}
+	return uniswapv3.EncodePath(r.Tokens, fees)
+}
+
+// format returns the route as a list of token symbols with the fee tiers of
+// the pools between them.
+func (r *Route) format(tokens map[types.Address]Token) string {
+	var b strings.Builder
+	for i, addr := range r.Tokens {
+		if i > 0 {
+			fmt.Fprintf(&b, " -(%d)-> ", r.Pools[i-1].Fee)
+		}
+		b.WriteString(tokens[addr].Symbol)
+	}
+	return b.String()
+}
+
+// router finds the best route for a swap. Pools are read once per token
+// pair and their tick data is reused across quotes.
+type router struct {
+	client   rpc.RPC
+	block    types.BlockNumber
+	deployer uniswapv3.PoolDeployer
+	fee      uint32
+	pools    map[[2]types.Address][]*Pool
+}
+
+// newRouter returns a router that uses the pools of the deployment at the
+// given block. If fee is not zero, only pools with that fee tier are used.
+func newRouter(client rpc.RPC, block types.BlockNumber, deployer uniswapv3.PoolDeployer, fee uint32) *router {
+	return &router{
+		client:   client,
+		block:    block,
+		deployer: deployer,
+		fee:      fee,
+		pools:    make(map[[2]types.Address][]*Pool),
+	}
+}
+
+// pairPools returns the initialized pools that swap tokenIn for tokenOut,
+// or nil if there are none.
+func (r *router) pairPools(ctx context.Context, tokenIn, tokenOut types.Address) ([]*Pool, error) {
+	key := [2]types.Address{tokenIn, tokenOut}
+	if pools, ok := r.pools[key]; ok {
+		return pools, nil
+	}
+	pools, err := findPools(ctx, r.client, r.block, r.deployer, tokenIn, tokenOut, r.fee)
+	var (
+		notFound       *PoolNotFoundError
+		notInitialized *PoolNotInitializedError
+	)
+	if errors.As(err, &notFound) || errors.As(err, &notInitialized) {
+		pools, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.pools[key] = pools
+	return pools, nil
+}
+
+// quote quotes selling amountIn through the path of tokens. For each hop,
+// the pool with the highest output is used. It returns nil if a hop has no
+// pool with enough liquidity.
+func (r *router) quote(ctx context.Context, path []types.Address, amountIn *big.Int) (*Route, error) {
+	route := &Route{Tokens: path, AmountIn: amountIn}
+	amount := amountIn
+	for i := 0; i < len(path)-1; i++ {
+		pools, err := r.pairPools(ctx, path[i], path[i+1])
+		if err != nil {
+			return nil, err
+		}
+		var (
+			bestPool *Pool
+			bestOut  *big.Int
+		)
+		for _, p := range pools {
+			zeroForOne := !p.Inverted
+			sqrtPriceLimitX96 := new(big.Int).Sub(uniswapv3.MaxSqrtRatio, big.NewInt(1))
+			if zeroForOne {
+				sqrtPriceLimitX96 = new(big.Int).Add(uniswapv3.MinSqrtRatio, big.NewInt(1))
+			}
+			res, err := simulateSwap(ctx, r.client, r.block, p, zeroForOne, amount, sqrtPriceLimitX96)
+			if errors.Is(err, uniswapv3.ErrInsufficientLiquidity) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			// A pool that cannot take the whole amount would revert the
+			// router swap.
+			if res.AmountIn.Cmp(amount) < 0 || res.AmountOut.Sign() == 0 {
+				continue
+			}
+			if bestOut == nil || res.AmountOut.Cmp(bestOut) > 0 {
+				bestPool, bestOut = p, res.AmountOut
+			}
+		}
+		if bestPool == nil {
+			return nil, nil
+		}
+		route.Pools = append(route.Pools, bestPool)
+		amount = bestOut
+	}
+	route.AmountOut = amount
+	return route, nil
+}
+
+// bestRoute quotes all routes from tokenIn to tokenOut with up to
+// maxRouteHops pools through the base tokens and returns the one with the
+// highest output.
+func (r *router) bestRoute(ctx context.Context, tokenIn, tokenOut types.Address, bases []types.Address, amountIn *big.Int) (*Route, error) {
+	var best *Route
+	for _, path := range routePaths(tokenIn, tokenOut, bases, maxRouteHops) {
+		route, err := r.quote(ctx, path, amountIn)
+		if err != nil {
+			return nil, err
+		}
+		if route != nil && (best == nil || route.AmountOut.Cmp(best.AmountOut) > 0) {
+			best = route
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no route with enough liquidity from %s to %s", tokenIn, tokenOut)
+	}
+	return best, nil
+}
+
+// routePaths returns all token paths from tokenIn to tokenOut with at most
+// maxHops hops whose intermediate tokens are distinct base tokens.
+func routePaths(tokenIn, tokenOut types.Address, bases []types.Address, maxHops int) [][]types.Address {
+	var (
+		paths [][]types.Address
+		walk  func(path []types.Address)
+	)
+	walk = func(path []types.Address) {
+		paths = append(paths, append(append([]types.Address{}, path...), tokenOut))
+		if len(path) == maxHops {
+			return
+		}
+		for _, base := range bases {
+			if base == tokenOut || containsAddress(path, base) {
+				continue
+			}
+			walk(append(path, base))
+		}
+	}
+	walk([]types.Address{tokenIn})
+	return paths
+}
+
+// containsAddress returns true if addr is in addrs.
+func containsAddress(addrs []types.Address, addr types.Address) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func runSwapRoute(ctx context.Context, args []string) error {
+	var (
+		opts            options
+		txOpts          txOptions
+		poolOpts        poolOptions
+		tokenIn         addressFlag
+		tokenOut        addressFlag
+		bases           addressListFlag
+		slippage        uint64
+		deadline        time.Duration
+		amountInStr     string
+		minAmountOutStr string
+		prec            int
+		twapWindow      time.Duration
+		maxTWAPDev      uint64
+	)
+	fs := flag.NewFlagSet("swap-route", flag.ContinueOnError)
+	opts.register(fs)
+	poolOpts.register(fs)
+	txOpts.register(fs)
+	fs.Var(&tokenIn, "token-in", "address of the token to sell")
+	fs.Var(&tokenOut, "token-out", "address of the token to buy")
+	fs.Var(&bases, "base", "address of a token routes may pass through (may be repeated, defaults to WETH, USDC and DAI)")
+	fs.Uint64Var(&slippage, "slippage", 50, "maximum difference from the quoted amount in basis points")
+	fs.DurationVar(&deadline, "deadline", 20*time.Minute, "time after which the router rejects the swap")
+	fs.StringVar(&amountInStr, "amount-in", "", "exact amount of tokens to sell")
+	fs.StringVar(&minAmountOutStr, "min-amount-out", "", "minimum amount of tokens to receive (defaults to the quote minus slippage)")
+	fs.IntVar(&prec, "precision", 8, "number of decimal places of printed prices")
+	fs.DurationVar(&twapWindow, "twap-window", 5*time.Minute, "window of the TWAP the spot price of every pool in the route is checked against (0 disables the check)")
+	fs.Uint64Var(&maxTWAPDev, "max-twap-deviation", 100, "maximum deviation of the spot price from the TWAP in basis points")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token-in": &tokenIn, "token-out": &tokenOut}); err != nil {
+		return err
+	}
+	if amountInStr == "" {
+		return errors.New("-amount-in is required")
+	}
+	if slippage >= 10000 {
+		return errors.New("-slippage must be lower than 10000")
+	}
+	if twapWindow < 0 {
+		return errors.New("-twap-window must not be negative")
+	}
+
+	deployer, err := poolOpts.deployer(opts.chainID)
+	if err != nil {
+		return err
+	}
+	if deployer.SwapRouter == (types.Address{}) {
+		return fmt.Errorf("no swap router is known for %s on chain %d", deployer.Name, opts.chainID)
+	}
+	if len(bases) == 0 {
+		bases = defaultBaseTokens[opts.chainID]
+	}
+
+	s, err := opts.newSession(ctx, !txOpts.dryRun)
+	if err != nil {
+		return err
+	}
+
+	// Get token information, including the base tokens to print routes.
+	tokenAddrs := []types.Address{tokenIn.addr, tokenOut.addr}
+	for _, base := range bases {
+		if !containsAddress(tokenAddrs, base) {
+			tokenAddrs = append(tokenAddrs, base)
+		}
+	}
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, tokenAddrs...)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, tokenIn.addr, tokenOut.addr); err != nil {
+		return err
+	}
+	in, out := tokens[tokenIn.addr], tokens[tokenOut.addr]
+	amountIn, err := parseAmount(amountInStr, in.Decimals)
+	if err != nil {
+		return err
+	}
+	if amountIn.Sign() == 0 {
+		return errors.New("amount must be greater than zero")
+	}
+	if amountIn.Cmp(in.Balance) > 0 {
+		return fmt.Errorf(
+			"insufficient %s balance: have %s, need %s",
+			in.Symbol, in.FormatAmount(in.Balance), in.FormatAmount(amountIn),
+		)
+	}
+
+	// Find the route with the highest output.
+	route, err := newRouter(s.client, s.block, deployer, uint32(poolOpts.fee)).bestRoute(ctx, tokenIn.addr, tokenOut.addr, bases, amountIn)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Route: %s\n", route.format(tokens))
+	fmt.Printf("Quoted amount out: %s\n", out.FormatAmount(route.AmountOut))
+
+	// The route is quoted from the spot prices of its pools, so check that
+	// none of them was moved away from its TWAP.
+	if twapWindow > 0 {
+		if err := tokensErr(tokens, route.Tokens...); err != nil {
+			return err
+		}
+		for i, pool := range route.Pools {
+			hopIn, hopOut := tokens[route.Tokens[i]], tokens[route.Tokens[i+1]]
+			if err := checkTWAP(ctx, s.client, s.block, pool, hopIn, hopOut, twapWindow, maxTWAPDev, prec); err != nil {
+				return fmt.Errorf("TWAP check of the %s/%s pool failed: %w (use -twap-window 0 to skip the check)", hopIn.Symbol, hopOut.Symbol, err)
+			}
+		}
+	}
+	minAmountOut := new(big.Int).Mul(route.AmountOut, big.NewInt(int64(10000-slippage)))
+	minAmountOut.Quo(minAmountOut, big.NewInt(10000))
+	if minAmountOutStr != "" {
+		if minAmountOut, err = parseAmount(minAmountOutStr, out.Decimals); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Minimum amount out: %s\n", out.FormatAmount(minAmountOut))
+
+	// Build the router call. The swap is wrapped in a multicall to enforce
+	// the deadline.
+	path, err := route.Path()
+	if err != nil {
+		return err
+	}
+	swapData, err := swapRouterExactInput.EncodeArgs(map[string]any{
+		"path":             path,
+		"recipient":        s.account,
+		"amountIn":         amountIn,
+		"amountOutMinimum": minAmountOut,
+	})
+	if err != nil {
+		return err
+	}
+	callData, err := swapRouterMulticall.EncodeArgs(big.NewInt(time.Now().Add(deadline).Unix()), [][]byte{swapData})
+	if err != nil {
+		return err
+	}
+
+	// Approve the router to spend only the amount needed for the swap.
+	routerAddr := deployer.SwapRouter
+	approved, err := s.approve(ctx, txOpts, tokenIn.addr, routerAddr, in, amountIn)
+	if err != nil {
+		return err
+	}
+
+	// Simulate and send the swap. The router enforces the minimum output
+	// itself, so a swap below it reverts.
+	tx := &types.Transaction{Call: types.Call{To: &routerAddr, Input: callData}}
+	decodeAmounts := func(returnData []byte) (*big.Int, *big.Int, error) {
+		amountOut, err := decodeRouterExactInput(returnData)
+		return amountIn, amountOut, err
+	}
+	return s.executeSwap(ctx, txOpts, tx, approved, tokenIn.addr, tokenOut.addr, in, out, decodeAmounts)
+}
+
+// decodeRouterExactInput decodes the amount out returned by a swap router
+// multicall with a single exact input call.
+func decodeRouterExactInput(returnData []byte) (*big.Int, error) {
+	var (
+		results   [][]byte
+		amountOut *big.Int
+	)
+	if err := swapRouterMulticall.DecodeValues(returnData, &results); err != nil {
+		return nil, err
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("expected 1 multicall result, got %d", len(results))
+	}
+	if err := swapRouterExactInput.DecodeValues(results[0], &amountOut); err != nil {
+		return nil, err
+	}
+	return amountOut, nil
+}
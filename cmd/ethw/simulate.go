@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/defiweb/go-eth/hexutil"
+
+	"workshop/txutil"
+)
+
+// printSimulation prints the result of a simulated transaction.
+func printSimulation(name string, sim *txutil.Simulation) {
+	fmt.Printf("%s simulation (dry run, nothing was sent):\n", name)
+	if sim.Reverted {
+		fmt.Printf("  Reverted:    %s\n", knownErrors.Decode(sim.RevertData))
+		return
+	}
+	fmt.Printf("  Return data: %s\n", hexutil.BytesToHex(sim.ReturnData))
+	fmt.Printf("  Gas:         %d\n", sim.Gas)
+	fmt.Printf("  Gas price:   %s gwei\n", formatAmount(sim.GasPrice, 9))
+	fmt.Printf("  Fee:         %s ETH\n", formatAmount(sim.Fee, 18))
+}
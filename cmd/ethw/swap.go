@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/txutil"
+	"workshop/uniswapv3"
+)
+
+func runSwap(ctx context.Context, args []string) error {
+	var (
+		opts            options
+		txOpts          txOptions
+		poolOpts        poolOptions
+		tokenIn         addressFlag
+		tokenOut        addressFlag
+		swapContract    = addressFlag{addr: SwapContract, set: true}
+		prec            int
+		limitTick       string
+		slippage        uint64
+		amountInStr     string
+		amountOutStr    string
+		minAmountOutStr string
+		maxAmountInStr  string
+		twapWindow      time.Duration
+		maxTWAPDev      uint64
+		feeds           feedListFlag
+		maxFeedDev      uint64
+		maxFeedAge      time.Duration
+		backendKind     string
+		deadline        time.Duration
+	)
+	fs := flag.NewFlagSet("swap", flag.ContinueOnError)
+	opts.register(fs)
+	poolOpts.register(fs)
+	txOpts.register(fs)
+	fs.Var(&tokenIn, "token-in", "address of the token to sell")
+	fs.Var(&tokenOut, "token-out", "address of the token to buy")
+	fs.Var(&swapContract, "swap-contract", "address of the swap wrapper contract")
+	fs.StringVar(&backendKind, "backend", backendWrapper, "contract that executes the swap: wrapper (the swap wrapper) or router (SwapRouter02)")
+	fs.DurationVar(&deadline, "deadline", 20*time.Minute, "time after which the router rejects the swap (router backend only)")
+	fs.IntVar(&prec, "precision", 8, "number of decimal places of printed prices")
+	fs.Uint64Var(&slippage, "slippage", 50, "maximum price slippage in basis points")
+	fs.StringVar(&limitTick, "limit-tick", "", "tick at which the swap stops (defaults to the price allowed by -slippage)")
+	fs.StringVar(&amountInStr, "amount-in", "", "exact amount of tokens to sell")
+	fs.StringVar(&amountOutStr, "amount-out", "", "exact amount of tokens to buy")
+	fs.StringVar(&minAmountOutStr, "min-amount-out", "", "minimum amount of tokens to receive with -amount-in (defaults to the spot price minus slippage)")
+	fs.StringVar(&maxAmountInStr, "max-amount-in", "", "maximum amount of tokens to sell with -amount-out (defaults to the spot price plus slippage)")
+	fs.DurationVar(&twapWindow, "twap-window", 5*time.Minute, "window of the TWAP the spot price is checked against (0 disables the check)")
+	fs.Uint64Var(&maxTWAPDev, "max-twap-deviation", 100, "maximum deviation of the spot price from the TWAP in basis points")
+	fs.Var(&feeds, "chainlink-feed", "Chainlink feed to check the pool price against, as base:quote:feed addresses (may be repeated)")
+	fs.Uint64Var(&maxFeedDev, "max-chainlink-deviation", 200, "maximum deviation of the pool price from the Chainlink price in basis points")
+	fs.DurationVar(&maxFeedAge, "chainlink-max-age", time.Hour, "maximum age of the Chainlink round")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token-in": &tokenIn, "token-out": &tokenOut}); err != nil {
+		return err
+	}
+	if (amountInStr == "") == (amountOutStr == "") {
+		return errors.New("exactly one of -amount-in or -amount-out is required")
+	}
+	if slippage >= 10000 {
+		return errors.New("-slippage must be lower than 10000")
+	}
+	if twapWindow < 0 {
+		return errors.New("-twap-window must not be negative")
+	}
+
+	deployer, err := poolOpts.deployer(opts.chainID)
+	if err != nil {
+		return err
+	}
+	backend := swapBackend{kind: backendKind, deadline: time.Now().Add(deadline)}
+	switch backendKind {
+	case backendWrapper:
+		backend.address = swapContract.addr
+	case backendRouter:
+		if deployer.SwapRouter == (types.Address{}) {
+			return fmt.Errorf("no swap router is known for %s on chain %d", deployer.Name, opts.chainID)
+		}
+		if limitTick != "" {
+			return errors.New("-limit-tick is not supported by the router backend")
+		}
+		backend.address = deployer.SwapRouter
+	default:
+		return fmt.Errorf("invalid -backend: %q", backendKind)
+	}
+
+	s, err := opts.newSession(ctx, !txOpts.dryRun)
+	if err != nil {
+		return err
+	}
+
+	// Get token information.
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, tokenIn.addr, tokenOut.addr); err != nil {
+		return err
+	}
+	in, out := tokens[tokenIn.addr], tokens[tokenOut.addr]
+
+	// Find the pool and read its current state.
+	pool, err := findPool(ctx, s.client, s.block, deployer, tokenIn.addr, tokenOut.addr, uint32(poolOpts.fee))
+	if err != nil {
+		return err
+	}
+	printPool(pool)
+	slot0, zeroForOne := pool.Slot0, !pool.Inverted
+
+	// Print the current price.
+	fmt.Printf("Current price: %s\n", uniswapv3.FormatPrice(poolPrice(slot0, pool.Inverted, in, out), prec))
+	fmt.Printf("Current tick: %d\n", slot0.Tick)
+
+	// Refuse to trade at a spot price that may have been manipulated.
+	if twapWindow > 0 {
+		if err := checkTWAP(ctx, s.client, s.block, pool, in, out, twapWindow, maxTWAPDev, prec); err != nil {
+			return fmt.Errorf("TWAP check failed: %w (use -twap-window 0 to skip the check)", err)
+		}
+	}
+	if feed, inverted, ok := feeds.find(tokenIn.addr, tokenOut.addr); ok {
+		spot := poolPrice(slot0, pool.Inverted, in, out)
+		if err := checkChainlink(ctx, s.client, s.block, feed, inverted, spot, maxFeedAge, maxFeedDev, prec); err != nil {
+			return fmt.Errorf("Chainlink check failed: %w", err)
+		}
+	}
+
+	// Compute the swap amounts and bounds. In the exact input mode, the
+	// amount of received tokens is bounded by minAmountOut. In the exact
+	// output mode, the amount of sold tokens is bounded by maxAmountIn.
+	var (
+		sqrtPriceLimitX96 = computeSqrtPriceLimitX96(slot0.SqrtPriceX96, zeroForOne, slippage)
+		exactOutput       = amountOutStr != ""
+		amountSpecified   *big.Int
+		minAmountOut      *big.Int
+		maxAmountIn       *big.Int
+	)
+	if backend.kind == backendRouter {
+		// The router swaps are sent without a price limit, so that they are
+		// filled in full or revert. They are simulated the same way.
+		sqrtPriceLimitX96 = new(big.Int).Sub(uniswapv3.MaxSqrtRatio, big.NewInt(1))
+		if zeroForOne {
+			sqrtPriceLimitX96 = new(big.Int).Add(uniswapv3.MinSqrtRatio, big.NewInt(1))
+		}
+	}
+	if limitTick != "" {
+		tick, err := strconv.Atoi(limitTick)
+		if err != nil {
+			return fmt.Errorf("invalid -limit-tick: %q", limitTick)
+		}
+		if sqrtPriceLimitX96, err = tickSqrtPriceLimitX96(tick, slot0.SqrtPriceX96, zeroForOne); err != nil {
+			return err
+		}
+	}
+	if exactOutput {
+		amountOut, err := parseAmount(amountOutStr, out.Decimals)
+		if err != nil {
+			return err
+		}
+		amountSpecified = new(big.Int).Neg(amountOut)
+		minAmountOut = amountOut
+		maxAmountIn = computeSpotAmountIn(slot0.SqrtPriceX96, amountOut, zeroForOne, pool.Fee)
+		maxAmountIn.Mul(maxAmountIn, big.NewInt(int64(10000+slippage)))
+		maxAmountIn = ceilDiv(maxAmountIn, big.NewInt(10000))
+		if maxAmountInStr != "" {
+			if maxAmountIn, err = parseAmount(maxAmountInStr, in.Decimals); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Maximum amount in: %s\n", in.FormatAmount(maxAmountIn))
+	} else {
+		amountIn, err := parseAmount(amountInStr, in.Decimals)
+		if err != nil {
+			return err
+		}
+		amountSpecified = amountIn
+		maxAmountIn = amountIn
+		minAmountOut = computeSpotAmountOut(slot0.SqrtPriceX96, amountIn, zeroForOne, pool.Fee)
+		minAmountOut.Mul(minAmountOut, big.NewInt(int64(10000-slippage)))
+		minAmountOut.Quo(minAmountOut, big.NewInt(10000))
+		if minAmountOutStr != "" {
+			if minAmountOut, err = parseAmount(minAmountOutStr, out.Decimals); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Minimum amount out: %s\n", out.FormatAmount(minAmountOut))
+	}
+	if maxAmountIn.Cmp(in.Balance) > 0 {
+		return fmt.Errorf(
+			"insufficient %s balance: have %s, need up to %s",
+			in.Symbol, in.FormatAmount(in.Balance), in.FormatAmount(maxAmountIn),
+		)
+	}
+
+	// Simulate the swap through the initialized ticks of the pool to get the
+	// expected amounts. The amounts are computed locally, so they do not
+	// depend on the data returned by the swap contract. The swap stops at the
+	// price limit, so less than the specified amount may be swapped.
+	expected, err := simulateSwap(ctx, s.client, s.block, pool, zeroForOne, amountSpecified, sqrtPriceLimitX96)
+	if err != nil {
+		return fmt.Errorf("unable to simulate the swap: %w", err)
+	}
+	fmt.Printf("Expected amount in: %s\n", in.FormatAmount(expected.AmountIn))
+	fmt.Printf("Expected amount out: %s\n", out.FormatAmount(expected.AmountOut))
+	if expected.AmountOut.Cmp(minAmountOut) < 0 {
+		return fmt.Errorf(
+			"expected output %s is below the minimum of %s",
+			out.FormatAmount(expected.AmountOut), out.FormatAmount(minAmountOut),
+		)
+	}
+	if expected.AmountIn.Cmp(maxAmountIn) > 0 {
+		return fmt.Errorf(
+			"expected input %s is above the maximum of %s",
+			in.FormatAmount(expected.AmountIn), in.FormatAmount(maxAmountIn),
+		)
+	}
+	if backend.kind == backendRouter && !exactOutput && expected.AmountIn.Cmp(amountSpecified) < 0 {
+		return fmt.Errorf("the pool does not have enough liquidity to swap %s", in.FormatAmount(amountSpecified))
+	}
+
+	// Approve the swap contract to spend only the amount needed for the swap.
+	approved, err := s.approve(ctx, txOpts, tokenIn.addr, backend.address, in, maxAmountIn)
+	if err != nil {
+		return err
+	}
+
+	// Simulate and send the swap.
+	swap := NewSwap(pool.Address, pool.Fee, tokenIn.addr, tokenOut.addr, amountSpecified)
+	tx, err := backend.Tx(swap, s.account, sqrtPriceLimitX96, minAmountOut, maxAmountIn)
+	if err != nil {
+		return err
+	}
+
+	// The swap wrapper does not return the amounts, while the router returns
+	// the amount that was not specified.
+	var decodeAmounts func(returnData []byte) (amountIn, amountOut *big.Int, err error)
+	if backend.kind == backendRouter {
+		decodeAmounts = swap.DecodeRouterAmounts
+	}
+	return s.executeSwap(ctx, txOpts, tx, approved, tokenIn.addr, tokenOut.addr, in, out, decodeAmounts)
+}
+
+// executeSwap simulates the swap transaction and, unless it is a dry run,
+// sends it and reports the amounts transferred by it. The simulation runs
+// against the latest block, so it includes an approval sent just before.
+// If decodeAmounts is not nil, it is used to print the amounts returned by
+// the simulated swap in a dry run.
+func (s *session) executeSwap(ctx context.Context, txOpts txOptions, tx *types.Transaction, approved bool, tokenInAddr, tokenOutAddr types.Address, in, out Token, decodeAmounts func(returnData []byte) (amountIn, amountOut *big.Int, err error)) error {
+	fmt.Printf("Swapping %s for %s\n", in.Symbol, out.Symbol)
+	simBlock := types.LatestBlockNumber
+	if txOpts.dryRun {
+		simBlock = s.block
+		if !approved {
+			fmt.Printf("Warning: the swap is simulated without the approval above, so it may revert\n")
+		}
+	}
+	sim, err := txutil.Simulate(ctx, s.client, *tx, s.account, simBlock)
+	if err != nil {
+		return err
+	}
+	if txOpts.dryRun {
+		printSimulation("Swap", sim)
+		if !sim.Reverted && decodeAmounts != nil {
+			amountIn, amountOut, err := decodeAmounts(sim.ReturnData)
+			if err != nil {
+				fmt.Printf("  Amounts:     unavailable, the swap did not return them\n")
+				return nil
+			}
+			fmt.Printf("  Amount in:   %s\n", in.FormatAmount(amountIn))
+			fmt.Printf("  Amount out:  %s\n", out.FormatAmount(amountOut))
+		}
+		return nil
+	}
+	if sim.Reverted {
+		return fmt.Errorf("swap would revert: %s", knownErrors.Decode(sim.RevertData))
+	}
+
+	// Swap tokens.
+	hash, _, err := s.client.SendTransaction(ctx, *tx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Swap TX hash: %s\n", hash.String())
+	fmt.Printf("Waiting for swap to be mined...\n")
+	receipt, err := txutil.WaitForReceipt(ctx, s.client, *hash, txOpts.waitOptions())
+	if err != nil {
+		return err
+	}
+
+	// Report the amounts actually transferred by the swap.
+	sent, err := sumTransfers(receipt.Logs, tokenInAddr, &s.account, nil)
+	if err != nil {
+		return err
+	}
+	received, err := sumTransfers(receipt.Logs, tokenOutAddr, nil, &s.account)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Swap complete!\n")
+	fmt.Printf("  Sent:     %s\n", in.FormatAmount(sent))
+	fmt.Printf("  Received: %s\n", out.FormatAmount(received))
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/multicall"
+	"workshop/uniswapv3"
+)
+
+// tickWordsPerLoad is the number of tick bitmap words loaded at once when a
+// simulated swap runs out of loaded words.
+const tickWordsPerLoad = 4
+
+// maxTickWords is the number of tick bitmap words a simulated swap may move
+// away from the current tick. A swap that needs more words is reported as
+// having insufficient liquidity, so that swaps in pools without liquidity do
+// not read the whole bitmap.
+const maxTickWords = 64
+
+// simulateSwap simulates a swap through the pool offline by traversing its
+// initialized ticks. The tick bitmap and the ticks are read at the given
+// block, starting around the current tick and loading more words in the
+// direction of the swap as needed. Loaded words are kept in the pool, so
+// they are not read again by later simulations. If the swap needs words
+// more than maxTickWords away from the current tick, an error wrapping
+// uniswapv3.ErrInsufficientLiquidity is returned.
+func simulateSwap(ctx context.Context, client rpc.RPC, block types.BlockNumber, pool *Pool, zeroForOne bool, amountSpecified, sqrtPriceLimitX96 *big.Int) (*uniswapv3.SwapResult, error) {
+	if pool.state == nil {
+		pool.state = &uniswapv3.PoolState{
+			SqrtPriceX96: pool.Slot0.SqrtPriceX96,
+			Tick:         int(pool.Slot0.Tick),
+			Liquidity:    pool.Liquidity,
+			Fee:          pool.Fee,
+			TickSpacing:  pool.TickSpacing,
+			TickBitmap:   make(uniswapv3.TickBitmap),
+			LiquidityNet: make(map[int]*big.Int),
+		}
+	}
+	state := pool.state
+
+	// Load the words next to the current tick in the direction of the swap.
+	startPos := uniswapv3.WordPosition(state.Tick, state.TickSpacing)
+	wordPos := startPos
+	for {
+		if distance := int(wordPos) - int(startPos); distance >= maxTickWords || distance <= -maxTickWords {
+			return nil, fmt.Errorf("%w: the swap needs more than %d tick bitmap words of pool %s", uniswapv3.ErrInsufficientLiquidity, maxTickWords, pool.Address)
+		}
+		if err := loadTickWords(ctx, client, block, pool.Address, state, wordPos, zeroForOne); err != nil {
+			return nil, err
+		}
+		res, err := state.Swap(zeroForOne, amountSpecified, sqrtPriceLimitX96)
+		var missing *uniswapv3.MissingWordError
+		if !errors.As(err, &missing) {
+			return res, err
+		}
+		wordPos = missing.WordPos
+	}
+}
+
+// loadTickWords reads tickWordsPerLoad bitmap words starting at wordPos and
+// moving left (down) or right (up), together with the liquidity of all
+// initialized ticks in them, and adds them to the state.
+func loadTickWords(ctx context.Context, client rpc.RPC, block types.BlockNumber, poolAddr types.Address, state *uniswapv3.PoolState, wordPos int16, down bool) error {
+	var (
+		positions []int16
+		words     []*big.Int
+		calls     []*multicall.Call
+	)
+	for i := int16(0); i < tickWordsPerLoad; i++ {
+		pos := wordPos + i
+		if down {
+			pos = wordPos - i
+		}
+		if _, ok := state.TickBitmap[pos]; ok {
+			continue
+		}
+		positions = append(positions, pos)
+	}
+	words = make([]*big.Int, len(positions))
+	for i, pos := range positions {
+		calls = append(calls, &multicall.Call{
+			Target:  poolAddr,
+			Method:  uniswapTickBitmap,
+			Args:    []any{pos},
+			Results: []any{&words[i]},
+		})
+	}
+	if err := aggregate(ctx, client, block, calls); err != nil {
+		return err
+	}
+
+	// Read the liquidity of the initialized ticks in the loaded words.
+	var (
+		ticks         []int
+		liquidityNets []*big.Int
+	)
+	for i, pos := range positions {
+		state.TickBitmap[pos] = words[i]
+		ticks = append(ticks, uniswapv3.InitializedTicks(pos, words[i], state.TickSpacing)...)
+	}
+	liquidityNets = make([]*big.Int, len(ticks))
+	calls = nil
+	for i, tick := range ticks {
+		calls = append(calls, &multicall.Call{
+			Target:  poolAddr,
+			Method:  uniswapTicks,
+			Args:    []any{tick},
+			Results: []any{nil, &liquidityNets[i], nil, nil, nil, nil, nil, nil},
+		})
+	}
+	if err := aggregate(ctx, client, block, calls); err != nil {
+		return err
+	}
+	for i, tick := range ticks {
+		state.LiquidityNet[tick] = liquidityNets[i]
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/erc20"
+	"workshop/multicall"
+)
+
+type Token struct {
+	Name     string
+	Symbol   string
+	Decimals uint8
+	Balance  *big.Int
+
+	// Err is set if the decimals or the balance of the token could not be
+	// read.
+	Err error
+}
+
+// FormatAmount formats an amount in the smallest token unit as a decimal
+// number followed by the token symbol.
+func (t Token) FormatAmount(x *big.Int) string {
+	return formatAmount(x, t.Decimals) + " " + t.Symbol
+}
+
+// sumTransfers returns the total amount of tokens transferred by the Transfer
+// events of the token in the logs. If from or to are not nil, only transfers
+// from or to the given addresses are counted.
+func sumTransfers(logs []types.Log, tokenAddr types.Address, from, to *types.Address) (*big.Int, error) {
+	sum := new(big.Int)
+	for _, log := range logs {
+		if log.Address != tokenAddr || len(log.Topics) == 0 || log.Topics[0] != erc20.TransferEvent.Topic0() {
+			continue
+		}
+		var (
+			logFrom, logTo types.Address
+			value          *big.Int
+		)
+		if err := erc20.TransferEvent.DecodeValues(log.Topics, log.Data, &logFrom, &logTo, &value); err != nil {
+			return nil, err
+		}
+		if (from != nil && logFrom != *from) || (to != nil && logTo != *to) {
+			continue
+		}
+		sum.Add(sum, value)
+	}
+	return sum, nil
+}
+
+// fetchTokens reads the name, symbol, decimals and balance of the account for each
+// of the given tokens. All values are read at the given block in a single
+// multicall.
+//
+// A failed call affects only its token. Tokens that do not implement name or
+// symbol, or return them in another format such as bytes32, get their
+// address as the name or symbol. If the decimals or the balance cannot be
+// read, the Err field of the token is set. The returned error is non-nil
+// only if the batch itself fails.
+func fetchTokens(ctx context.Context, client rpc.RPC, block types.BlockNumber, accountAddr types.Address, tokenAddrs ...types.Address) (map[types.Address]Token, error) {
+	var (
+		tokens = make([]Token, len(tokenAddrs))
+		calls  []*multicall.Call
+	)
+	for i, address := range tokenAddrs {
+		calls = append(
+			calls,
+			&multicall.Call{Target: address, Method: erc20.NameMethod, Results: []any{&tokens[i].Name}},
+			&multicall.Call{Target: address, Method: erc20.SymbolMethod, Results: []any{&tokens[i].Symbol}},
+			&multicall.Call{Target: address, Method: erc20.DecimalsMethod, Results: []any{&tokens[i].Decimals}},
+			&multicall.Call{Target: address, Method: erc20.BalanceOfMethod, Args: []any{accountAddr}, Results: []any{&tokens[i].Balance}},
+		)
+	}
+	if err := multicall.Aggregate(ctx, client, block, calls); err != nil {
+		return nil, err
+	}
+
+	var result = make(map[types.Address]Token)
+	for i, address := range tokenAddrs {
+		name, symbol, decimals, balance := calls[4*i], calls[4*i+1], calls[4*i+2], calls[4*i+3]
+		token := tokens[i]
+		if name.Err != nil {
+			token.Name = address.String()
+		}
+		if symbol.Err != nil {
+			token.Symbol = address.String()
+		}
+		switch {
+		case decimals.Err != nil:
+			token.Err = fmt.Errorf("unable to read the decimals of token %s: %w", address, decimals.Err)
+		case balance.Err != nil:
+			token.Err = fmt.Errorf("unable to read the balance of token %s: %w", address, balance.Err)
+		}
+		result[address] = token
+	}
+	return result, nil
+}
+
+// tokensErr returns the error of the first of the given tokens that could not
+// be read by fetchTokens.
+func tokensErr(tokens map[types.Address]Token, tokenAddrs ...types.Address) error {
+	for _, address := range tokenAddrs {
+		if err := tokens[address].Err; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+func runTokenInfo(ctx context.Context, args []string) error {
+	var (
+		opts   options
+		tokens addressListFlag
+	)
+	fs := flag.NewFlagSet("token-info", flag.ContinueOnError)
+	opts.register(fs)
+	fs.Var(&tokens, "token", "token address, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return errors.New("-token is required")
+	}
+
+	s, err := opts.newSession(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	info, err := fetchTokens(ctx, s.client, s.block, s.account, tokens...)
+	if err != nil {
+		return err
+	}
+	for _, address := range tokens {
+		token := info[address]
+		fmt.Printf("Token: %s\n", address.String())
+		fmt.Printf("  Name:     %s\n", token.Name)
+		fmt.Printf("  Symbol:   %s\n", token.Symbol)
+		if token.Err != nil {
+			fmt.Printf("  Error:    %v\n", token.Err)
+			continue
+		}
+		fmt.Printf("  Decimals: %d\n", token.Decimals)
+		fmt.Printf("  Balance:  %s\n", formatAmount(token.Balance, token.Decimals))
+	}
+	return nil
+}
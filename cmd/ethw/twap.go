@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/multicall"
+	"workshop/uniswapv3"
+)
+
+// OracleHistoryError is returned by fetchTWAP when the pool oracle does not
+// store observations old enough to cover the requested window.
+type OracleHistoryError struct {
+	Pool        types.Address
+	Window      time.Duration
+	Available   time.Duration
+	Cardinality uint16
+}
+
+// Error implements the error interface.
+func (e *OracleHistoryError) Error() string {
+	return fmt.Sprintf(
+		"pool %s stores %s of price history in %d observations, which is shorter than the %s window",
+		e.Pool, e.Available, e.Cardinality, e.Window,
+	)
+}
+
+// oracleObservation is a single observation stored by the pool oracle.
+type oracleObservation struct {
+	BlockTimestamp                    uint32
+	TickCumulative                    int64
+	SecondsPerLiquidityCumulativeX128 *big.Int
+	Initialized                       bool
+}
+
+// fetchTWAP returns the time-weighted average tick of the pool over the
+// window ending at the given block.
+//
+// The pool oracle stores a limited number of observations, so the history
+// is checked first and an *OracleHistoryError is returned if it does not
+// cover the window.
+func fetchTWAP(ctx context.Context, client rpc.RPC, block types.BlockNumber, pool *Pool, window time.Duration) (int, error) {
+	secondsAgo := int64(window / time.Second)
+	if secondsAgo <= 0 || secondsAgo > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid TWAP window: %s", window)
+	}
+	b, err := client.BlockByNumber(ctx, block, false)
+	if err != nil {
+		return 0, err
+	}
+
+	// The observations are stored in a ring buffer, so the oldest one is
+	// the one after the latest, unless the buffer has not been filled yet.
+	var (
+		cardinality = pool.Slot0.ObservationCardinality
+		next, first oracleObservation
+	)
+	if cardinality == 0 {
+		return 0, fmt.Errorf("pool %s has no oracle observations", pool.Address)
+	}
+	calls := []*multicall.Call{
+		observationCall(pool.Address, (uint64(pool.Slot0.ObservationIndex)+1)%uint64(cardinality), &next),
+		observationCall(pool.Address, 0, &first),
+	}
+	if err := aggregate(ctx, client, block, calls); err != nil {
+		return 0, err
+	}
+	oldest := next
+	if !oldest.Initialized {
+		oldest = first
+	}
+	available := time.Duration(uint32(b.Timestamp.Unix())-oldest.BlockTimestamp) * time.Second
+	if available < time.Duration(secondsAgo)*time.Second {
+		return 0, &OracleHistoryError{
+			Pool:        pool.Address,
+			Window:      window,
+			Available:   available,
+			Cardinality: cardinality,
+		}
+	}
+
+	var tickCumulatives []int64
+	call := &multicall.Call{
+		Target:  pool.Address,
+		Method:  uniswapObserve,
+		Args:    []any{[]uint32{uint32(secondsAgo), 0}},
+		Results: []any{&tickCumulatives, nil},
+	}
+	if err := aggregate(ctx, client, block, []*multicall.Call{call}); err != nil {
+		return 0, err
+	}
+	if len(tickCumulatives) != 2 {
+		return 0, fmt.Errorf("observe returned %d tick cumulatives, expected 2", len(tickCumulatives))
+	}
+	return uniswapv3.ArithmeticMeanTick(tickCumulatives[0], tickCumulatives[1], uint32(secondsAgo)), nil
+}
+
+// checkTWAP compares the spot price of the pool to its average price over
+// the window and returns an error if they differ by more than maxBps basis
+// points. The spot price can be moved within a single block, while moving
+// the average price requires holding the spot price for the whole window.
+func checkTWAP(ctx context.Context, client rpc.RPC, block types.BlockNumber, pool *Pool, tokenIn, tokenOut Token, window time.Duration, maxBps uint64, prec int) error {
+	meanTick, err := fetchTWAP(ctx, client, block, pool, window)
+	if err != nil {
+		return err
+	}
+	twap, err := tickPrice(meanTick, pool.Inverted, tokenIn, tokenOut)
+	if err != nil {
+		return err
+	}
+	spot := poolPrice(pool.Slot0, pool.Inverted, tokenIn, tokenOut)
+	deviation := priceDeviationBps(spot, twap)
+	fmt.Printf("TWAP price (%s): %s\n", window, uniswapv3.FormatPrice(twap, prec))
+	fmt.Printf("Spot price deviation: %s%%\n", new(big.Rat).Quo(deviation, big.NewRat(100, 1)).FloatString(2))
+	if deviation.Cmp(new(big.Rat).SetUint64(maxBps)) > 0 {
+		return fmt.Errorf(
+			"spot price deviates from the %s TWAP by more than %d basis points, the pool may be manipulated",
+			window, maxBps,
+		)
+	}
+	return nil
+}
+
+// priceDeviationBps returns the absolute difference between the price and
+// the reference price in basis points of the reference price.
+func priceDeviationBps(price, reference *big.Rat) *big.Rat {
+	if reference.Sign() == 0 {
+		return new(big.Rat).SetInt64(math.MaxInt64)
+	}
+	d := new(big.Rat).Sub(price, reference)
+	d.Abs(d)
+	d.Quo(d, reference)
+	return d.Mul(d, big.NewRat(10000, 1))
+}
+
+// observationCall returns a call that reads the oracle observation at the
+// given index into obs.
+func observationCall(poolAddr types.Address, index uint64, obs *oracleObservation) *multicall.Call {
+	return &multicall.Call{
+		Target: poolAddr,
+		Method: uniswapObservations,
+		Args:   []any{index},
+		Results: []any{
+			&obs.BlockTimestamp,
+			&obs.TickCumulative,
+			&obs.SecondsPerLiquidityCumulativeX128,
+			&obs.Initialized,
+		},
+	}
+}
+
+// tickPrice returns the price of tokenIn expressed in tokenOut at the given
+// pool tick.
+func tickPrice(tick int, inverted bool, tokenIn, tokenOut Token) (*big.Rat, error) {
+	sqrtPriceX96, err := uniswapv3.GetSqrtRatioAtTick(tick)
+	if err != nil {
+		return nil, err
+	}
+	return poolPrice(UniswapSlot0{SqrtPriceX96: sqrtPriceX96}, inverted, tokenIn, tokenOut), nil
+}
+
+func runTWAP(ctx context.Context, args []string) error {
+	var (
+		opts     options
+		poolOpts poolOptions
+		tokenIn  addressFlag
+		tokenOut addressFlag
+		prec     int
+		window   time.Duration
+	)
+	fs := flag.NewFlagSet("twap", flag.ContinueOnError)
+	opts.register(fs)
+	poolOpts.register(fs)
+	fs.Var(&tokenIn, "token-in", "address of the token to sell")
+	fs.Var(&tokenOut, "token-out", "address of the token to buy")
+	fs.IntVar(&prec, "precision", 8, "number of decimal places of printed prices")
+	fs.DurationVar(&window, "window", 30*time.Minute, "time window of the average price")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token-in": &tokenIn, "token-out": &tokenOut}); err != nil {
+		return err
+	}
+	if window < time.Second {
+		return errors.New("-window must be at least one second")
+	}
+
+	deployer, err := poolOpts.deployer(opts.chainID)
+	if err != nil {
+		return err
+	}
+
+	s, err := opts.newSession(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, tokenIn.addr, tokenOut.addr); err != nil {
+		return err
+	}
+	in, out := tokens[tokenIn.addr], tokens[tokenOut.addr]
+
+	// Find the pool and read its current state.
+	pool, err := findPool(ctx, s.client, s.block, deployer, tokenIn.addr, tokenOut.addr, uint32(poolOpts.fee))
+	if err != nil {
+		return err
+	}
+	printPool(pool)
+	fmt.Printf("Current price: %s\n", uniswapv3.FormatPrice(poolPrice(pool.Slot0, pool.Inverted, in, out), prec))
+	fmt.Printf("Current tick: %d\n", pool.Slot0.Tick)
+
+	// Read the average tick from the pool oracle.
+	meanTick, err := fetchTWAP(ctx, s.client, s.block, pool, window)
+	if err != nil {
+		return err
+	}
+	price, err := tickPrice(meanTick, pool.Inverted, in, out)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("TWAP window: %s\n", window)
+	fmt.Printf("TWAP tick: %d\n", meanTick)
+	fmt.Printf("TWAP price: %s\n", uniswapv3.FormatPrice(price, prec))
+	return nil
+}
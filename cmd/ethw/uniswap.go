@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/uniswapv3"
+)
+
+// SwapContract is the address of the workshop Uniswap V3 swap wrapper.
+var SwapContract = types.MustAddressFromHex("0x1aa862951c58aEc5f2745F63575d91BaCCF8fc41")
+
+var (
+	// PancakeSwap V3 pools return feeProtocol as uint32, while Uniswap V3
+	// pools return it as uint8. Both are padded to 32 bytes, so declaring it
+	// as uint32 decodes the values returned by either pool.
+	uniswapSlot0 = abi.MustParseMethod(`
+		function slot0() public view returns (
+			uint160 sqrtPriceX96, 
+			int24 tick, 
+			uint16 observationIndex, 
+			uint16 observationCardinality, 
+			uint16 observationCardinalityNext, 
+			uint32 feeProtocol, 
+			bool unlocked
+		)
+	`)
+
+	uniswapLiquidity = abi.MustParseMethod(`
+		function liquidity() public view returns (uint128)
+	`)
+
+	uniswapFee = abi.MustParseMethod(`
+		function fee() public view returns (uint24)
+	`)
+
+	uniswapTickSpacing = abi.MustParseMethod(`
+		function tickSpacing() public view returns (int24)
+	`)
+
+	uniswapTickBitmap = abi.MustParseMethod(`
+		function tickBitmap(int16 wordPosition) public view returns (uint256)
+	`)
+
+	uniswapTicks = abi.MustParseMethod(`
+		function ticks(int24 tick) public view returns (
+			uint128 liquidityGross,
+			int128 liquidityNet,
+			uint256 feeGrowthOutside0X128,
+			uint256 feeGrowthOutside1X128,
+			int56 tickCumulativeOutside,
+			uint160 secondsPerLiquidityOutsideX128,
+			uint32 secondsOutside,
+			bool initialized
+		)
+	`)
+
+	uniswapObserve = abi.MustParseMethod(`
+		function observe(uint32[] secondsAgos) public view returns (
+			int56[] tickCumulatives,
+			uint160[] secondsPerLiquidityCumulativeX128s
+		)
+	`)
+
+	uniswapObservations = abi.MustParseMethod(`
+		function observations(uint256 index) public view returns (
+			uint32 blockTimestamp,
+			int56 tickCumulative,
+			uint160 secondsPerLiquidityCumulativeX128,
+			bool initialized
+		)
+	`)
+
+	uniswapGetPool = abi.MustParseMethod(`
+		function getPool(address tokenA, address tokenB, uint24 fee) external view returns (address pool)
+	`)
+
+	quoterQuoteExactInputSingle = abi.MustParseMethod(`
+		function quoteExactInputSingle(
+			(address tokenIn, address tokenOut, uint256 amountIn, uint24 fee, uint160 sqrtPriceLimitX96) params
+		) returns (
+			uint256 amountOut,
+			uint160 sqrtPriceX96After,
+			uint32 initializedTicksCrossed,
+			uint256 gasEstimate
+		)
+	`)
+
+	quoterQuoteExactOutputSingle = abi.MustParseMethod(`
+		function quoteExactOutputSingle(
+			(address tokenIn, address tokenOut, uint256 amount, uint24 fee, uint160 sqrtPriceLimitX96) params
+		) returns (
+			uint256 amountIn,
+			uint160 sqrtPriceX96After,
+			uint32 initializedTicksCrossed,
+			uint256 gasEstimate
+		)
+	`)
+
+	swapRouterExactInput = abi.MustParseMethod(`
+		function exactInput(
+			(bytes path, address recipient, uint256 amountIn, uint256 amountOutMinimum) params
+		) payable returns (
+			uint256 amountOut
+		)
+	`)
+
+	swapRouterExactInputSingle = abi.MustParseMethod(`
+		function exactInputSingle(
+			(
+				address tokenIn,
+				address tokenOut,
+				uint24 fee,
+				address recipient,
+				uint256 amountIn,
+				uint256 amountOutMinimum,
+				uint160 sqrtPriceLimitX96
+			) params
+		) payable returns (
+			uint256 amountOut
+		)
+	`)
+
+	swapRouterExactOutputSingle = abi.MustParseMethod(`
+		function exactOutputSingle(
+			(
+				address tokenIn,
+				address tokenOut,
+				uint24 fee,
+				address recipient,
+				uint256 amountOut,
+				uint256 amountInMaximum,
+				uint160 sqrtPriceLimitX96
+			) params
+		) payable returns (
+			uint256 amountIn
+		)
+	`)
+
+	swapRouterMulticall = abi.MustParseMethod(`
+		function multicall(uint256 deadline, bytes[] data) payable returns (bytes[] results)
+	`)
+
+	uniswapSwap = abi.MustParseMethod(`
+		function swap(
+			address pool,
+			address recipient,
+			bool zeroForOne,
+			int256 amountSpecified,
+			uint160 sqrtPriceLimitX96
+		)
+	`)
+)
+
+type UniswapSlot0 struct {
+	SqrtPriceX96               *big.Int `abi:"sqrtPriceX96"`
+	Tick                       int32    `abi:"tick"`
+	ObservationIndex           uint16   `abi:"observationIndex"`
+	ObservationCardinality     uint16   `abi:"observationCardinality"`
+	ObservationCardinalityNext uint16   `abi:"observationCardinalityNext"`
+	FeeProtocol                uint32   `abi:"feeProtocol"`
+	Unlocked                   bool     `abi:"unlocked"`
+}
+
+// Swap is a swap of TokenIn for TokenOut through a Uniswap V3 pool.
+type Swap struct {
+	Pool     types.Address
+	Fee      uint32
+	TokenIn  types.Address
+	TokenOut types.Address
+
+	// Amount is the exact amount of TokenIn to sell if positive, or the exact
+	// amount of TokenOut to buy if negative.
+	Amount *big.Int
+}
+
+// NewSwap returns a swap of tokenIn for tokenOut through the given pool. A
+// positive amount is the exact amount of tokenIn to sell, a negative one is
+// the exact amount of tokenOut to buy.
+func NewSwap(pool types.Address, fee uint32, tokenIn, tokenOut types.Address, amount *big.Int) Swap {
+	return Swap{Pool: pool, Fee: fee, TokenIn: tokenIn, TokenOut: tokenOut, Amount: amount}
+}
+
+// ZeroForOne returns true if the swap sells token0 of the pool for token1.
+func (s Swap) ZeroForOne() bool {
+	_, _, inverted := uniswapv3.SortTokens(s.TokenIn, s.TokenOut)
+	return !inverted
+}
+
+// Tx builds the swap transaction for the swap wrapper contract.
+func (s Swap) Tx(swapAddr, recipientAddr types.Address, sqrtPriceLimitX96 *big.Int) (*types.Transaction, error) {
+	callData, err := uniswapSwap.EncodeArgs(s.Pool, recipientAddr, s.ZeroForOne(), s.Amount, sqrtPriceLimitX96)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Transaction{
+		Call: types.Call{
+			To:    &swapAddr,
+			Input: callData,
+		},
+	}, nil
+}
+
+// RouterTx builds the swap transaction for the SwapRouter02 contract. The
+// swap is wrapped in a multicall that reverts after the deadline. The router
+// reverts if less than minAmountOut is received in the exact input mode, or
+// more than maxAmountIn is sold in the exact output mode. The swap is sent
+// without a price limit, because with a limit the router may fill only a part
+// of the swap, while the amount bounds above already limit the price.
+func (s Swap) RouterTx(routerAddr, recipientAddr types.Address, minAmountOut, maxAmountIn *big.Int, deadline time.Time) (*types.Transaction, error) {
+	var (
+		swapData []byte
+		err      error
+	)
+	params := map[string]any{
+		"tokenIn":           s.TokenIn,
+		"tokenOut":          s.TokenOut,
+		"fee":               s.Fee,
+		"recipient":         recipientAddr,
+		"sqrtPriceLimitX96": big.NewInt(0),
+	}
+	if s.Amount.Sign() < 0 {
+		params["amountOut"] = new(big.Int).Neg(s.Amount)
+		params["amountInMaximum"] = maxAmountIn
+		swapData, err = swapRouterExactOutputSingle.EncodeArgs(params)
+	} else {
+		params["amountIn"] = s.Amount
+		params["amountOutMinimum"] = minAmountOut
+		swapData, err = swapRouterExactInputSingle.EncodeArgs(params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	callData, err := swapRouterMulticall.EncodeArgs(big.NewInt(deadline.Unix()), [][]byte{swapData})
+	if err != nil {
+		return nil, err
+	}
+	return &types.Transaction{
+		Call: types.Call{
+			To:    &routerAddr,
+			Input: callData,
+		},
+	}, nil
+}
+
+// DecodeRouterAmounts decodes the data returned by the SwapRouter02
+// multicall built by RouterTx into the amount of TokenIn sent and the amount
+// of TokenOut received. The router returns only the amount that was not
+// specified. The specified amount is swapped in full, because RouterTx sets
+// no price limit.
+func (s Swap) DecodeRouterAmounts(returnData []byte) (amountIn, amountOut *big.Int, err error) {
+	var results [][]byte
+	if err := swapRouterMulticall.DecodeValues(returnData, &results); err != nil {
+		return nil, nil, err
+	}
+	if len(results) != 1 {
+		return nil, nil, fmt.Errorf("expected 1 multicall result, got %d", len(results))
+	}
+	if s.Amount.Sign() < 0 {
+		if err := swapRouterExactOutputSingle.DecodeValues(results[0], &amountIn); err != nil {
+			return nil, nil, err
+		}
+		return amountIn, new(big.Int).Neg(s.Amount), nil
+	}
+	if err := swapRouterExactInputSingle.DecodeValues(results[0], &amountOut); err != nil {
+		return nil, nil, err
+	}
+	return s.Amount, amountOut, nil
+}
+
+// Swap backends, the contracts that execute swaps.
+const (
+	backendWrapper = "wrapper" // the workshop swap wrapper contract
+	backendRouter  = "router"  // the SwapRouter02 contract of the deployment
+)
+
+// swapBackend is the contract that executes swaps. The same approval and
+// quoting logic is used for all backends.
+type swapBackend struct {
+	kind    string
+	address types.Address
+
+	// deadline is the time after which the router rejects the swap. It is
+	// not supported by the swap wrapper.
+	deadline time.Time
+}
+
+// Tx builds the swap transaction for the backend. The price limit is used
+// only by the swap wrapper.
+func (b swapBackend) Tx(s Swap, recipientAddr types.Address, sqrtPriceLimitX96, minAmountOut, maxAmountIn *big.Int) (*types.Transaction, error) {
+	if b.kind == backendRouter {
+		return s.RouterTx(b.address, recipientAddr, minAmountOut, maxAmountIn, b.deadline)
+	}
+	return s.Tx(b.address, recipientAddr, sqrtPriceLimitX96)
+}
+
+// computeSqrtPriceLimitX96 returns the sqrt price limit for a swap that may
+// move the pool price at most slippageBps basis points away from the current
+// sqrtPriceX96. Swaps of token0 for token1 (zeroForOne) decrease the price,
+// swaps in the other direction increase it.
+func computeSqrtPriceLimitX96(sqrtPriceX96 *big.Int, zeroForOne bool, slippageBps uint64) *big.Int {
+	// The price is the square of the sqrt price, so the limit is
+	// sqrtPriceX96 * sqrt(1 ± slippage), which is computed exactly as
+	// sqrt(sqrtPriceX96^2 * (10000 ± slippageBps) / 10000).
+	var factor *big.Int
+	if zeroForOne {
+		if slippageBps >= 10000 {
+			return new(big.Int).Add(uniswapv3.MinSqrtRatio, big.NewInt(1))
+		}
+		factor = new(big.Int).SetUint64(10000 - slippageBps)
+	} else {
+		factor = new(big.Int).SetUint64(10000 + slippageBps)
+	}
+	limit := new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96)
+	limit.Mul(limit, factor)
+	limit.Quo(limit, big.NewInt(10000))
+	limit.Sqrt(limit)
+	if zeroForOne && limit.Cmp(uniswapv3.MinSqrtRatio) <= 0 {
+		return new(big.Int).Add(uniswapv3.MinSqrtRatio, big.NewInt(1))
+	}
+	if !zeroForOne && limit.Cmp(uniswapv3.MaxSqrtRatio) >= 0 {
+		return new(big.Int).Sub(uniswapv3.MaxSqrtRatio, big.NewInt(1))
+	}
+	return limit
+}
+
+// tickSqrtPriceLimitX96 returns the sqrt price limit at the given tick. The
+// limit must lie on the side of the current price the swap moves towards.
+func tickSqrtPriceLimitX96(tick int, sqrtPriceX96 *big.Int, zeroForOne bool) (*big.Int, error) {
+	limit, err := uniswapv3.GetSqrtRatioAtTick(tick)
+	if err != nil {
+		return nil, err
+	}
+	if zeroForOne && (limit.Cmp(sqrtPriceX96) >= 0 || limit.Cmp(uniswapv3.MinSqrtRatio) <= 0) {
+		return nil, fmt.Errorf("limit tick %d must be below the current price", tick)
+	}
+	if !zeroForOne && (limit.Cmp(sqrtPriceX96) <= 0 || limit.Cmp(uniswapv3.MaxSqrtRatio) >= 0) {
+		return nil, fmt.Errorf("limit tick %d must be above the current price", tick)
+	}
+	return limit, nil
+}
+
+// computeSpotAmountOut returns the amount of tokens received for amountIn at
+// the current pool price after the pool fee is paid. The price impact of
+// the swap is ignored.
+func computeSpotAmountOut(sqrtPriceX96, amountIn *big.Int, zeroForOne bool, fee uint32) *big.Int {
+	// The price of token0 in token1 is sqrtPriceX96^2 / 2^192.
+	priceNum := new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96)
+	priceDen := new(big.Int).Lsh(big.NewInt(1), 192)
+	if !zeroForOne {
+		priceNum, priceDen = priceDen, priceNum
+	}
+	out := new(big.Int).Mul(amountIn, big.NewInt(int64(1e6-fee)))
+	out.Mul(out, priceNum)
+	return out.Quo(out, priceDen.Mul(priceDen, big.NewInt(1e6)))
+}
+
+// computeSpotAmountIn returns the amount of tokens that must be sold to
+// receive amountOut at the current pool price, including the pool fee. The
+// price impact of the swap is ignored. The result is rounded up.
+func computeSpotAmountIn(sqrtPriceX96, amountOut *big.Int, zeroForOne bool, fee uint32) *big.Int {
+	priceNum := new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96)
+	priceDen := new(big.Int).Lsh(big.NewInt(1), 192)
+	if !zeroForOne {
+		priceNum, priceDen = priceDen, priceNum
+	}
+	num := new(big.Int).Mul(amountOut, priceDen)
+	num.Mul(num, big.NewInt(1e6))
+	den := new(big.Int).Mul(priceNum, big.NewInt(int64(1e6-fee)))
+	return ceilDiv(num, den)
+}
+
+// ceilDiv returns x / y rounded up, for positive x and y.
+func ceilDiv(x, y *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
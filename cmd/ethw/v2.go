@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/multicall"
+	"workshop/uniswapv2"
+	"workshop/uniswapv3"
+)
+
+// Pair is the state of an Uniswap V2 pair, with the reserves ordered by the
+// direction of the trade.
+type Pair struct {
+	Address    types.Address
+	ReserveIn  *big.Int
+	ReserveOut *big.Int
+}
+
+// v2Deployment returns the Uniswap V2 deployment on the chain.
+func v2Deployment(chainID uint64) (uniswapv2.Deployment, error) {
+	d, ok := uniswapv2.Deployments[chainID]
+	if !ok {
+		return uniswapv2.Deployment{}, fmt.Errorf("Uniswap V2 is not deployed on chain %d", chainID)
+	}
+	return d, nil
+}
+
+// findPair returns the Uniswap V2 pair of tokenIn and tokenOut. The pair
+// address is computed locally and cross-checked against the factory.
+func findPair(ctx context.Context, client rpc.RPC, block types.BlockNumber, d uniswapv2.Deployment, tokenIn, tokenOut types.Address) (*Pair, error) {
+	var (
+		pairAddr           = d.PairAddress(tokenIn, tokenOut)
+		factoryPair        types.Address
+		reserve0, reserve1 *big.Int
+		blockTimestamp     uint32
+	)
+	calls := []*multicall.Call{
+		{Target: d.Factory, Method: uniswapv2.GetPairMethod, Args: []any{tokenIn, tokenOut}, Results: []any{&factoryPair}},
+		{Target: pairAddr, Method: uniswapv2.GetReservesMethod, Results: []any{&reserve0, &reserve1, &blockTimestamp}},
+	}
+	if err := multicall.Aggregate(ctx, client, block, calls); err != nil {
+		return nil, err
+	}
+	if calls[0].Err != nil {
+		return nil, calls[0].Err
+	}
+	token0, token1, inverted := uniswapv2.SortTokens(tokenIn, tokenOut)
+	if factoryPair == (types.Address{}) {
+		return nil, &PoolNotFoundError{Token0: token0, Token1: token1}
+	}
+	if factoryPair != pairAddr {
+		return nil, fmt.Errorf("factory returned pair %s, but %s was computed", factoryPair, pairAddr)
+	}
+	if calls[1].Err != nil {
+		return nil, calls[1].Err
+	}
+	if inverted {
+		return &Pair{Address: pairAddr, ReserveIn: reserve1, ReserveOut: reserve0}, nil
+	}
+	return &Pair{Address: pairAddr, ReserveIn: reserve0, ReserveOut: reserve1}, nil
+}
+
+// printPair prints the address and reserves of a pair.
+func printPair(p *Pair, tokenIn, tokenOut Token) {
+	fmt.Printf("Pair address: %s\n", p.Address.String())
+	fmt.Printf("Reserves: %s, %s\n", tokenIn.FormatAmount(p.ReserveIn), tokenOut.FormatAmount(p.ReserveOut))
+}
+
+func runPriceV2(ctx context.Context, args []string) error {
+	var (
+		opts     options
+		tokenIn  addressFlag
+		tokenOut addressFlag
+		prec     int
+	)
+	fs := flag.NewFlagSet("price-v2", flag.ContinueOnError)
+	opts.register(fs)
+	fs.Var(&tokenIn, "token-in", "address of the token to sell")
+	fs.Var(&tokenOut, "token-out", "address of the token to buy")
+	fs.IntVar(&prec, "precision", 8, "number of decimal places of printed prices")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token-in": &tokenIn, "token-out": &tokenOut}); err != nil {
+		return err
+	}
+
+	deployment, err := v2Deployment(opts.chainID)
+	if err != nil {
+		return err
+	}
+
+	s, err := opts.newSession(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, tokenIn.addr, tokenOut.addr); err != nil {
+		return err
+	}
+	in, out := tokens[tokenIn.addr], tokens[tokenOut.addr]
+
+	pair, err := findPair(ctx, s.client, s.block, deployment, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	printPair(pair, in, out)
+
+	price := uniswapv2.Price(pair.ReserveIn, pair.ReserveOut, in.Decimals, out.Decimals)
+	fmt.Printf("Current price: %s\n", uniswapv3.FormatPrice(price, prec))
+	return nil
+}
+
+func runSwapV2(ctx context.Context, args []string) error {
+	var (
+		opts            options
+		txOpts          txOptions
+		tokenIn         addressFlag
+		tokenOut        addressFlag
+		prec            int
+		slippage        uint64
+		deadline        time.Duration
+		amountInStr     string
+		amountOutStr    string
+		minAmountOutStr string
+		maxAmountInStr  string
+	)
+	fs := flag.NewFlagSet("swap-v2", flag.ContinueOnError)
+	opts.register(fs)
+	txOpts.register(fs)
+	fs.Var(&tokenIn, "token-in", "address of the token to sell")
+	fs.Var(&tokenOut, "token-out", "address of the token to buy")
+	fs.IntVar(&prec, "precision", 8, "number of decimal places of printed prices")
+	fs.Uint64Var(&slippage, "slippage", 50, "maximum difference from the quoted amounts in basis points")
+	fs.DurationVar(&deadline, "deadline", 20*time.Minute, "time after which the router rejects the swap")
+	fs.StringVar(&amountInStr, "amount-in", "", "exact amount of tokens to sell")
+	fs.StringVar(&amountOutStr, "amount-out", "", "exact amount of tokens to buy")
+	fs.StringVar(&minAmountOutStr, "min-amount-out", "", "minimum amount of tokens to receive with -amount-in (defaults to the quote minus slippage)")
+	fs.StringVar(&maxAmountInStr, "max-amount-in", "", "maximum amount of tokens to sell with -amount-out (defaults to the quote plus slippage)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := requireFlags(map[string]*addressFlag{"token-in": &tokenIn, "token-out": &tokenOut}); err != nil {
+		return err
+	}
+	if (amountInStr == "") == (amountOutStr == "") {
+		return errors.New("exactly one of -amount-in or -amount-out is required")
+	}
+	if slippage >= 10000 {
+		return errors.New("-slippage must be lower than 10000")
+	}
+
+	deployment, err := v2Deployment(opts.chainID)
+	if err != nil {
+		return err
+	}
+
+	s, err := opts.newSession(ctx, !txOpts.dryRun)
+	if err != nil {
+		return err
+	}
+
+	// Get token information.
+	tokens, err := fetchTokens(ctx, s.client, s.block, s.account, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	if err := tokensErr(tokens, tokenIn.addr, tokenOut.addr); err != nil {
+		return err
+	}
+	in, out := tokens[tokenIn.addr], tokens[tokenOut.addr]
+
+	// Find the pair and read its reserves.
+	router := deployment.Router
+	pair, err := findPair(ctx, s.client, s.block, deployment, tokenIn.addr, tokenOut.addr)
+	if err != nil {
+		return err
+	}
+	printPair(pair, in, out)
+	price := uniswapv2.Price(pair.ReserveIn, pair.ReserveOut, in.Decimals, out.Decimals)
+	fmt.Printf("Current price: %s\n", uniswapv3.FormatPrice(price, prec))
+
+	// Quote the swap and build the router call. In the exact input mode, the
+	// amount of received tokens is bounded by minAmountOut. In the exact
+	// output mode, the amount of sold tokens is bounded by maxAmountIn.
+	var (
+		path         = []types.Address{tokenIn.addr, tokenOut.addr}
+		deadlineTime = big.NewInt(time.Now().Add(deadline).Unix())
+		minAmountOut *big.Int
+		maxAmountIn  *big.Int
+		callData     []byte
+	)
+	if amountOutStr != "" {
+		amountOut, err := parseAmount(amountOutStr, out.Decimals)
+		if err != nil {
+			return err
+		}
+		quoteIn, err := uniswapv2.GetAmountIn(amountOut, pair.ReserveIn, pair.ReserveOut)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Quoted amount in: %s\n", in.FormatAmount(quoteIn))
+		minAmountOut = amountOut
+		maxAmountIn = quoteIn.Mul(quoteIn, big.NewInt(int64(10000+slippage)))
+		maxAmountIn = ceilDiv(maxAmountIn, big.NewInt(10000))
+		if maxAmountInStr != "" {
+			if maxAmountIn, err = parseAmount(maxAmountInStr, in.Decimals); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Maximum amount in: %s\n", in.FormatAmount(maxAmountIn))
+		callData, err = uniswapv2.SwapTokensForExactTokensMethod.EncodeArgs(amountOut, maxAmountIn, path, s.account, deadlineTime)
+		if err != nil {
+			return err
+		}
+	} else {
+		amountIn, err := parseAmount(amountInStr, in.Decimals)
+		if err != nil {
+			return err
+		}
+		quoteOut := uniswapv2.GetAmountOut(amountIn, pair.ReserveIn, pair.ReserveOut)
+		fmt.Printf("Quoted amount out: %s\n", out.FormatAmount(quoteOut))
+		maxAmountIn = amountIn
+		minAmountOut = quoteOut.Mul(quoteOut, big.NewInt(int64(10000-slippage)))
+		minAmountOut.Quo(minAmountOut, big.NewInt(10000))
+		if minAmountOutStr != "" {
+			if minAmountOut, err = parseAmount(minAmountOutStr, out.Decimals); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Minimum amount out: %s\n", out.FormatAmount(minAmountOut))
+		callData, err = uniswapv2.SwapExactTokensForTokensMethod.EncodeArgs(amountIn, minAmountOut, path, s.account, deadlineTime)
+		if err != nil {
+			return err
+		}
+	}
+	if maxAmountIn.Cmp(in.Balance) > 0 {
+		return fmt.Errorf(
+			"insufficient %s balance: have %s, need up to %s",
+			in.Symbol, in.FormatAmount(in.Balance), in.FormatAmount(maxAmountIn),
+		)
+	}
+
+	// Approve the router to spend only the amount needed for the swap.
+	approved, err := s.approve(ctx, txOpts, tokenIn.addr, router, in, maxAmountIn)
+	if err != nil {
+		return err
+	}
+
+	// Simulate and send the swap. The router enforces the bounds itself, so a
+	// swap outside of them reverts.
+	tx := &types.Transaction{Call: types.Call{To: &router, Input: callData}}
+	return s.executeSwap(ctx, txOpts, tx, approved, tokenIn.addr, tokenOut.addr, in, out, decodeSwapV2Amounts)
+}
+
+// decodeSwapV2Amounts decodes the amounts returned by a router swap into the
+// amount of the first token sent and the amount of the last token received.
+func decodeSwapV2Amounts(returnData []byte) (amountIn, amountOut *big.Int, err error) {
+	var amounts []*big.Int
+	if err := uniswapv2.SwapExactTokensForTokensMethod.DecodeValues(returnData, &amounts); err != nil {
+		return nil, nil, err
+	}
+	if len(amounts) < 2 {
+		return nil, nil, fmt.Errorf("expected at least 2 amounts, got %d", len(amounts))
+	}
+	return amounts[0], amounts[len(amounts)-1], nil
+}
@@ -0,0 +1,195 @@
+// Package erc20 provides a client for ERC20 token contracts.
+package erc20
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+)
+
+// ERC20 methods. They are exported so that calls can be batched, for example
+// using the multicall package.
+var (
+	NameMethod         = abi.MustParseMethod(`function name() public view returns (string)`)
+	SymbolMethod       = abi.MustParseMethod(`function symbol() public view returns (string)`)
+	DecimalsMethod     = abi.MustParseMethod(`function decimals() public view returns (uint8)`)
+	TotalSupplyMethod  = abi.MustParseMethod(`function totalSupply() public view returns (uint256)`)
+	BalanceOfMethod    = abi.MustParseMethod(`function balanceOf(address account) public view returns (uint256)`)
+	AllowanceMethod    = abi.MustParseMethod(`function allowance(address owner, address spender) public view returns (uint256)`)
+	ApproveMethod      = abi.MustParseMethod(`function approve(address spender, uint256 amount) public returns (bool)`)
+	TransferMethod     = abi.MustParseMethod(`function transfer(address to, uint256 amount) public returns (bool)`)
+	TransferFromMethod = abi.MustParseMethod(`function transferFrom(address from, address to, uint256 amount) public returns (bool)`)
+)
+
+// TransferEvent is the ERC20 Transfer event.
+var TransferEvent = abi.MustParseEvent(`event Transfer(address indexed from, address indexed to, uint256 value)`)
+
+// Token is a client for a single ERC20 token contract.
+type Token struct {
+	client  rpc.RPC
+	address types.Address
+	block   types.BlockNumber
+}
+
+// New returns a new client for the ERC20 token at the given address.
+//
+// Read methods query the latest block, use At to query a different block.
+func New(client rpc.RPC, address types.Address) *Token {
+	return &Token{client: client, address: address, block: types.LatestBlockNumber}
+}
+
+// At returns a copy of the token client whose read methods query the state
+// at the given block.
+func (t *Token) At(block types.BlockNumber) *Token {
+	cpy := *t
+	cpy.block = block
+	return &cpy
+}
+
+// Address returns the address of the token contract.
+func (t *Token) Address() types.Address {
+	return t.address
+}
+
+// Name calls the name method of the token.
+func (t *Token) Name(ctx context.Context) (name string, err error) {
+	if err := t.call(ctx, NameMethod, []any{&name}); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Symbol calls the symbol method of the token.
+func (t *Token) Symbol(ctx context.Context) (symbol string, err error) {
+	if err := t.call(ctx, SymbolMethod, []any{&symbol}); err != nil {
+		return "", err
+	}
+	return symbol, nil
+}
+
+// Decimals calls the decimals method of the token.
+func (t *Token) Decimals(ctx context.Context) (decimals uint8, err error) {
+	if err := t.call(ctx, DecimalsMethod, []any{&decimals}); err != nil {
+		return 0, err
+	}
+	return decimals, nil
+}
+
+// TotalSupply calls the totalSupply method of the token.
+func (t *Token) TotalSupply(ctx context.Context) (supply *big.Int, err error) {
+	if err := t.call(ctx, TotalSupplyMethod, []any{&supply}); err != nil {
+		return nil, err
+	}
+	return supply, nil
+}
+
+// BalanceOf calls the balanceOf method of the token.
+func (t *Token) BalanceOf(ctx context.Context, account types.Address) (balance *big.Int, err error) {
+	if err := t.call(ctx, BalanceOfMethod, []any{&balance}, account); err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// Allowance calls the allowance method of the token.
+func (t *Token) Allowance(ctx context.Context, owner, spender types.Address) (allowance *big.Int, err error) {
+	if err := t.call(ctx, AllowanceMethod, []any{&allowance}, owner, spender); err != nil {
+		return nil, err
+	}
+	return allowance, nil
+}
+
+// Approve sends an approve transaction that allows the spender to transfer
+// up to the given amount of tokens from the sender's account.
+func (t *Token) Approve(ctx context.Context, spender types.Address, amount *big.Int) (*types.Hash, error) {
+	tx, err := t.ApproveTx(spender, amount)
+	if err != nil {
+		return nil, err
+	}
+	return t.send(ctx, ApproveMethod, tx)
+}
+
+// ApproveTx returns the transaction sent by Approve without sending it.
+func (t *Token) ApproveTx(spender types.Address, amount *big.Int) (*types.Transaction, error) {
+	return t.tx(ApproveMethod, spender, amount)
+}
+
+// Transfer sends a transfer transaction that moves the given amount of tokens
+// from the sender's account to the recipient.
+func (t *Token) Transfer(ctx context.Context, to types.Address, amount *big.Int) (*types.Hash, error) {
+	tx, err := t.TransferTx(to, amount)
+	if err != nil {
+		return nil, err
+	}
+	return t.send(ctx, TransferMethod, tx)
+}
+
+// TransferTx returns the transaction sent by Transfer without sending it.
+func (t *Token) TransferTx(to types.Address, amount *big.Int) (*types.Transaction, error) {
+	return t.tx(TransferMethod, to, amount)
+}
+
+// TransferFrom sends a transferFrom transaction that moves the given amount
+// of tokens from one account to another using the sender's allowance.
+func (t *Token) TransferFrom(ctx context.Context, from, to types.Address, amount *big.Int) (*types.Hash, error) {
+	tx, err := t.TransferFromTx(from, to, amount)
+	if err != nil {
+		return nil, err
+	}
+	return t.send(ctx, TransferFromMethod, tx)
+}
+
+// TransferFromTx returns the transaction sent by TransferFrom without
+// sending it.
+func (t *Token) TransferFromTx(from, to types.Address, amount *big.Int) (*types.Transaction, error) {
+	return t.tx(TransferFromMethod, from, to, amount)
+}
+
+// call executes a read-only method call and decodes the returned values
+// into results.
+func (t *Token) call(ctx context.Context, method *abi.Method, results []any, args ...any) error {
+	callData, err := method.EncodeArgs(args...)
+	if err != nil {
+		return fmt.Errorf("erc20: unable to encode %s arguments: %w", method.Name(), err)
+	}
+	response, _, err := t.client.Call(
+		ctx,
+		types.Call{To: &t.address, Input: callData},
+		t.block,
+	)
+	if err != nil {
+		return fmt.Errorf("erc20: %s call failed: %w", method.Name(), err)
+	}
+	if err := method.DecodeValues(response, results...); err != nil {
+		return fmt.Errorf("erc20: unable to decode %s result: %w", method.Name(), err)
+	}
+	return nil
+}
+
+// tx builds a transaction that calls the given method.
+func (t *Token) tx(method *abi.Method, args ...any) (*types.Transaction, error) {
+	callData, err := method.EncodeArgs(args...)
+	if err != nil {
+		return nil, fmt.Errorf("erc20: unable to encode %s arguments: %w", method.Name(), err)
+	}
+	address := t.address
+	return &types.Transaction{
+		Call: types.Call{
+			To:    &address,
+			Input: callData,
+		},
+	}, nil
+}
+
+// send sends a transaction built by tx.
+func (t *Token) send(ctx context.Context, method *abi.Method, tx *types.Transaction) (*types.Hash, error) {
+	hash, _, err := t.client.SendTransaction(ctx, *tx)
+	if err != nil {
+		return nil, fmt.Errorf("erc20: %s transaction failed: %w", method.Name(), err)
+	}
+	return hash, nil
+}
@@ -0,0 +1,163 @@
+// Package multicall batches contract calls into a single eth_call using the
+// Multicall3 contract.
+package multicall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/revert"
+)
+
+// Address is the address of the Multicall3 contract. It is deployed at the
+// same address on most EVM chains.
+var Address = types.MustAddressFromHex("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+var aggregate3 = abi.MustParseMethod(`
+	function aggregate3(
+		(address target, bool allowFailure, bytes callData)[] calls
+	) public payable returns (
+		(bool success, bytes returnData)[] returnData
+	)
+`)
+
+// Call is a single contract call executed by Aggregate.
+type Call struct {
+	Target  types.Address // Target is the address of the called contract.
+	Method  *abi.Method   // Method is the called method.
+	Args    []any         // Args are the method arguments.
+	Results []any         // Results are pointers to values to decode the returned values into.
+
+	// Err is set by Aggregate if the call reverted or its result could not
+	// be decoded.
+	Err error
+}
+
+// CallError is returned for a call that reverted.
+type CallError struct {
+	Target types.Address
+	Method *abi.Method
+	Data   []byte // Data is the revert data returned by the call.
+}
+
+// Error implements the error interface.
+func (e *CallError) Error() string {
+	if len(e.Data) == 0 {
+		return fmt.Sprintf("multicall: %s call to %s reverted", e.Method.Name(), e.Target)
+	}
+	return fmt.Sprintf("multicall: %s call to %s reverted: %s", e.Method.Name(), e.Target, hexutil.BytesToHex(e.Data))
+}
+
+// RevertData returns the revert data returned by the call.
+func (e *CallError) RevertData() []byte {
+	return e.Data
+}
+
+type call3 struct {
+	Target       types.Address `abi:"target"`
+	AllowFailure bool          `abi:"allowFailure"`
+	CallData     []byte        `abi:"callData"`
+}
+
+type result3 struct {
+	Success    bool   `abi:"success"`
+	ReturnData []byte `abi:"returnData"`
+}
+
+// Aggregate executes all calls in a single Multicall3 aggregate3 call at the
+// given block. If Multicall3 is not deployed at that block, for example
+// because the block is older than the contract, the calls are executed one
+// by one with plain eth_calls instead.
+//
+// Failing calls do not cause the whole batch to fail. Instead, the Err field
+// of the failed call is set. The returned error is non-nil only if the
+// batch itself could not be executed.
+func Aggregate(ctx context.Context, client rpc.RPC, block types.BlockNumber, calls []*Call) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	encoded := make([]call3, len(calls))
+	for i, c := range calls {
+		callData, err := c.Method.EncodeArgs(c.Args...)
+		if err != nil {
+			return fmt.Errorf("multicall: unable to encode %s arguments: %w", c.Method.Name(), err)
+		}
+		encoded[i] = call3{Target: c.Target, AllowFailure: true, CallData: callData}
+	}
+	callData, err := aggregate3.EncodeArgs(encoded)
+	if err != nil {
+		return fmt.Errorf("multicall: unable to encode aggregate3 arguments: %w", err)
+	}
+	response, _, err := client.Call(
+		ctx,
+		types.Call{To: &Address, Input: callData},
+		block,
+	)
+	if err != nil {
+		return fmt.Errorf("multicall: aggregate3 call failed: %w", err)
+	}
+	if len(response) == 0 {
+		// Calls to an address without code succeed with no data, while
+		// aggregate3 always returns an array.
+		code, err := client.GetCode(ctx, Address, block)
+		if err != nil {
+			return fmt.Errorf("multicall: unable to read Multicall3 code: %w", err)
+		}
+		if len(code) == 0 {
+			return callEach(ctx, client, block, calls, encoded)
+		}
+	}
+	var results []result3
+	if err := aggregate3.DecodeValues(response, &results); err != nil {
+		return fmt.Errorf("multicall: unable to decode aggregate3 result: %w", err)
+	}
+	if len(results) != len(calls) {
+		return fmt.Errorf("multicall: expected %d results, got %d", len(calls), len(results))
+	}
+	for i, c := range calls {
+		c.Err = nil
+		if !results[i].Success {
+			c.Err = &CallError{Target: c.Target, Method: c.Method, Data: results[i].ReturnData}
+			continue
+		}
+		if err := c.Method.DecodeValues(results[i].ReturnData, c.Results...); err != nil {
+			c.Err = fmt.Errorf("multicall: unable to decode %s result from %s: %w", c.Method.Name(), c.Target, err)
+		}
+	}
+	return nil
+}
+
+// callEach executes the encoded calls one by one. It is used at blocks at
+// which Multicall3 is not deployed.
+func callEach(ctx context.Context, client rpc.RPC, block types.BlockNumber, calls []*Call, encoded []call3) error {
+	for i, c := range calls {
+		c.Err = nil
+		response, _, err := client.Call(
+			ctx,
+			types.Call{To: &encoded[i].Target, Input: encoded[i].CallData},
+			block,
+		)
+		if err != nil {
+			// Reverted calls are reported by the node as RPC errors. Other
+			// errors mean that the call could not be executed at all.
+			var rpcErr *transport.RPCError
+			if !errors.As(err, &rpcErr) {
+				return fmt.Errorf("multicall: %s call to %s failed: %w", c.Method.Name(), c.Target, err)
+			}
+			data, _ := revert.DataFromError(err)
+			c.Err = &CallError{Target: c.Target, Method: c.Method, Data: data}
+			continue
+		}
+		if err := c.Method.DecodeValues(response, c.Results...); err != nil {
+			c.Err = fmt.Errorf("multicall: unable to decode %s result from %s: %w", c.Method.Name(), c.Target, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package revert decodes the revert data returned by failed contract calls
+// and transactions.
+package revert
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+)
+
+// panicReasons explains the Solidity panic codes.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler inserted panic",
+	0x01: "assertion failed",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array encoding",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero-initialized internal function",
+}
+
+// Error is an error with decoded revert data.
+type Error struct {
+	Data   []byte // Data is the raw revert data.
+	Reason string // Reason is the human-readable revert reason.
+	Err    error  // Err is the underlying error, may be nil.
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return "execution reverted: " + e.Reason
+	}
+	return fmt.Sprintf("%v (revert reason: %s)", e.Err, e.Reason)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// DataError is implemented by errors that carry revert data.
+type DataError interface {
+	error
+	RevertData() []byte
+}
+
+// Registry is a registry of custom error definitions used to decode revert
+// data. A nil registry decodes only the Error(string) and Panic(uint256)
+// errors.
+type Registry struct {
+	errors map[abi.FourBytes]*abi.Error
+}
+
+// NewRegistry returns a new registry with the given custom errors.
+func NewRegistry(errs ...*abi.Error) *Registry {
+	r := &Registry{errors: make(map[abi.FourBytes]*abi.Error)}
+	r.Register(errs...)
+	return r
+}
+
+// ParseRegistry returns a new registry with custom errors parsed from the
+// given signatures, e.g. "error InsufficientBalance(uint256 available)".
+func ParseRegistry(signatures ...string) (*Registry, error) {
+	r := NewRegistry()
+	for _, sig := range signatures {
+		e, err := abi.ParseError(sig)
+		if err != nil {
+			return nil, fmt.Errorf("revert: invalid error signature %q: %w", sig, err)
+		}
+		r.Register(e)
+	}
+	return r, nil
+}
+
+// MustParseRegistry is like ParseRegistry but panics on error.
+func MustParseRegistry(signatures ...string) *Registry {
+	r, err := ParseRegistry(signatures...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Register adds custom errors to the registry.
+func (r *Registry) Register(errs ...*abi.Error) {
+	for _, e := range errs {
+		r.errors[e.FourBytes()] = e
+	}
+}
+
+// Decode returns the human-readable reason for the given revert data.
+func (r *Registry) Decode(data []byte) string {
+	switch {
+	case len(data) == 0:
+		return "no revert data"
+	case abi.IsRevert(data):
+		return fmt.Sprintf("%q", abi.DecodeRevert(data))
+	case abi.IsPanic(data):
+		code := abi.DecodePanic(data)
+		if code == nil {
+			break
+		}
+		if code.IsUint64() {
+			if reason, ok := panicReasons[code.Uint64()]; ok {
+				return fmt.Sprintf("panic: %s (0x%x)", reason, code)
+			}
+		}
+		return fmt.Sprintf("panic: unknown code 0x%x", code)
+	case len(data) >= 4 && r != nil:
+		var selector abi.FourBytes
+		copy(selector[:], data[:4])
+		if e, ok := r.errors[selector]; ok {
+			if reason, err := decodeCustom(e, data[4:]); err == nil {
+				return reason
+			}
+		}
+	}
+	return "unknown error " + hexutil.BytesToHex(data)
+}
+
+// Wrap returns an *Error with decoded revert reason if err carries revert
+// data. Otherwise, err is returned unchanged.
+func (r *Registry) Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	var revertErr *Error
+	if errors.As(err, &revertErr) {
+		return err
+	}
+	data, ok := DataFromError(err)
+	if !ok {
+		return err
+	}
+	return &Error{Data: data, Reason: r.Decode(data), Err: err}
+}
+
+// DataFromError extracts revert data from an error returned by an RPC call
+// or from an error that implements DataError.
+func DataFromError(err error) ([]byte, bool) {
+	var dataErr DataError
+	if errors.As(err, &dataErr) {
+		return dataErr.RevertData(), true
+	}
+	var rpcErr *transport.RPCError
+	if !errors.As(err, &rpcErr) {
+		return nil, false
+	}
+	// Most nodes return the revert data as a hex string, but some of them
+	// wrap it in an object.
+	data := rpcErr.Data
+	if m, ok := data.(map[string]any); ok {
+		data = m["data"]
+	}
+	s, ok := data.(string)
+	if !ok || !strings.HasPrefix(s, "0x") {
+		return nil, false
+	}
+	b, err := hexutil.HexToBytes(s)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// decodeCustom formats a custom error with its arguments.
+func decodeCustom(e *abi.Error, data []byte) (string, error) {
+	elems := e.Inputs().Elements()
+	vals := make([]any, len(elems))
+	ptrs := make([]any, len(elems))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	// The abi.Error.DecodeValues method cannot be used because in go-eth
+	// v0.4.1 it rejects data with a matching selector.
+	if err := abi.DecodeValues(e.Inputs(), data, ptrs...); err != nil {
+		return "", err
+	}
+	args := make([]string, len(elems))
+	for i, elem := range elems {
+		arg := formatValue(vals[i])
+		if elem.Name != "" {
+			arg = elem.Name + "=" + arg
+		}
+		args[i] = arg
+	}
+	return e.Name() + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// formatValue formats a decoded ABI value.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return hexutil.BytesToHex(v)
+	case *big.Int:
+		return v.String()
+	case types.Address:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
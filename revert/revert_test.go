@@ -0,0 +1,215 @@
+package revert
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+)
+
+// errorData returns the revert data of the error with the given signature
+// and arguments.
+func errorData(signature string, args ...any) []byte {
+	e := abi.MustParseError(signature)
+	data, err := abi.EncodeValues(e.Inputs(), args...)
+	if err != nil {
+		panic(err)
+	}
+	selector := e.FourBytes()
+	return append(selector[:], data...)
+}
+
+// dataError is a DataError used in tests.
+type dataError struct {
+	data []byte
+}
+
+func (e dataError) Error() string      { return "data error" }
+func (e dataError) RevertData() []byte { return e.data }
+
+func TestRegistryDecode(t *testing.T) {
+	r := MustParseRegistry(
+		"error InsufficientBalance(uint256 available, uint256 required)",
+		"error Unauthorized(address)",
+	)
+	var (
+		truncated    = errorData("error InsufficientBalance(uint256 available, uint256 required)", big.NewInt(100), big.NewInt(200))[:36]
+		unregistered = errorData("error Expired(uint256)", big.NewInt(1))
+	)
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{
+			name:     "no data",
+			data:     nil,
+			expected: "no revert data",
+		},
+		{
+			name:     "error string",
+			data:     errorData("error Error(string)", "STF"),
+			expected: `"STF"`,
+		},
+		{
+			name:     "empty error string",
+			data:     errorData("error Error(string)", ""),
+			expected: `""`,
+		},
+		{
+			name:     "unknown panic code",
+			data:     errorData("error Panic(uint256)", big.NewInt(0x99)),
+			expected: "panic: unknown code 0x99",
+		},
+		{
+			name:     "custom error with named arguments",
+			data:     errorData("error InsufficientBalance(uint256 available, uint256 required)", big.NewInt(100), big.NewInt(200)),
+			expected: "InsufficientBalance(available=100, required=200)",
+		},
+		{
+			name:     "custom error with an unnamed argument",
+			data:     errorData("error Unauthorized(address)", types.MustAddressFromHex("0x1aa862951c58aEc5f2745F63575d91BaCCF8fc41")),
+			expected: "Unauthorized(0x1aa862951c58aec5f2745f63575d91baccf8fc41)",
+		},
+		{
+			name:     "custom error with invalid arguments",
+			data:     truncated,
+			expected: "unknown error " + hexutil.BytesToHex(truncated),
+		},
+		{
+			name:     "unregistered selector",
+			data:     unregistered,
+			expected: "unknown error " + hexutil.BytesToHex(unregistered),
+		},
+		{
+			name:     "data shorter than a selector",
+			data:     []byte{0x01, 0x02},
+			expected: "unknown error 0x0102",
+		},
+	}
+	for code, reason := range panicReasons {
+		tests = append(tests, struct {
+			name     string
+			data     []byte
+			expected string
+		}{
+			name:     fmt.Sprintf("panic code 0x%x", code),
+			data:     errorData("error Panic(uint256)", new(big.Int).SetUint64(code)),
+			expected: fmt.Sprintf("panic: %s (0x%x)", reason, code),
+		})
+	}
+	for _, tt := range tests {
+		if got := r.Decode(tt.data); got != tt.expected {
+			t.Errorf("%s: got %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestNilRegistryDecode(t *testing.T) {
+	var r *Registry
+	data := errorData("error InsufficientBalance(uint256 available, uint256 required)", big.NewInt(100), big.NewInt(200))
+	if got, expected := r.Decode(data), "unknown error "+hexutil.BytesToHex(data); got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+	if got, expected := r.Decode(errorData("error Error(string)", "STF")), `"STF"`; got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestDataFromError(t *testing.T) {
+	data := errorData("error Error(string)", "STF")
+	hex := hexutil.BytesToHex(data)
+	tests := []struct {
+		name     string
+		err      error
+		expected []byte
+		ok       bool
+	}{
+		{
+			name:     "data as a string",
+			err:      &transport.RPCError{Code: 3, Message: "execution reverted", Data: hex},
+			expected: data,
+			ok:       true,
+		},
+		{
+			name:     "data nested in an object",
+			err:      &transport.RPCError{Code: -32000, Message: "execution reverted", Data: map[string]any{"data": hex}},
+			expected: data,
+			ok:       true,
+		},
+		{
+			name:     "wrapped RPC error",
+			err:      fmt.Errorf("call failed: %w", &transport.RPCError{Code: 3, Data: hex}),
+			expected: data,
+			ok:       true,
+		},
+		{
+			name:     "DataError",
+			err:      fmt.Errorf("call failed: %w", dataError{data: data}),
+			expected: data,
+			ok:       true,
+		},
+		{
+			name: "data without a 0x prefix",
+			err:  &transport.RPCError{Code: 3, Data: hex[2:]},
+		},
+		{
+			name: "invalid hex data",
+			err:  &transport.RPCError{Code: 3, Data: "0xzz"},
+		},
+		{
+			name: "data of another type",
+			err:  &transport.RPCError{Code: 3, Data: 42},
+		},
+		{
+			name: "no data",
+			err:  &transport.RPCError{Code: -32000, Message: "nonce too low"},
+		},
+		{
+			name: "not an RPC error",
+			err:  errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		got, ok := DataFromError(tt.err)
+		if ok != tt.ok || string(got) != string(tt.expected) {
+			t.Errorf("%s: got %x, %t, expected %x, %t", tt.name, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestRegistryWrap(t *testing.T) {
+	r := MustParseRegistry("error InsufficientBalance(uint256 available, uint256 required)")
+	data := errorData("error InsufficientBalance(uint256 available, uint256 required)", big.NewInt(100), big.NewInt(200))
+	rpcErr := &transport.RPCError{Code: 3, Message: "execution reverted", Data: hexutil.BytesToHex(data)}
+
+	err := r.Wrap(rpcErr)
+	var revertErr *Error
+	if !errors.As(err, &revertErr) {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	if revertErr.Reason != "InsufficientBalance(available=100, required=200)" {
+		t.Errorf("unexpected reason %q", revertErr.Reason)
+	}
+	if !errors.Is(err, rpcErr) {
+		t.Error("expected the wrapped error to unwrap to the RPC error")
+	}
+
+	// Errors that are already decoded or carry no revert data are returned
+	// unchanged.
+	if r.Wrap(err) != err {
+		t.Error("expected an *Error to be returned unchanged")
+	}
+	plain := errors.New("connection refused")
+	if r.Wrap(plain) != plain {
+		t.Error("expected an error without revert data to be returned unchanged")
+	}
+	if r.Wrap(nil) != nil {
+		t.Error("expected nil for a nil error")
+	}
+}
@@ -181,21 +181,12 @@ func main() {
 	fmt.Printf("Current price: %f\n", currentPrice)
 
 	// Swap tokens.
-	if inverted {
-		fmt.Printf("Swapping %s for %s\n", tokens[tokenOut].Name, tokens[tokenIn].Name)
-		hash, err := sendUniswapSwap(ctx, client, inverted, poolAddress, key.Address(), tokens[tokenIn].Balance)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Printf("Swap TX hash: %s\n", hash.String())
-	} else {
-		fmt.Printf("Swapping %s for %s\n", tokens[tokenOut].Name, tokens[tokenIn].Name)
-		hash, err := sendUniswapSwap(ctx, client, inverted, poolAddress, key.Address(), tokens[tokenIn].Balance)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Printf("Swap TX hash: %s\n", hash.String())
+	fmt.Printf("Swapping %s for %s\n", tokens[tokenIn].Name, tokens[tokenOut].Name)
+	hash, err := sendUniswapSwap(ctx, client, inverted, poolAddress, key.Address(), tokens[tokenIn].Balance)
+	if err != nil {
+		panic(err)
 	}
+	fmt.Printf("Swap TX hash: %s\n", hash.String())
 }
 
 // callERC20Name calls the name method of an ERC20 token.
@@ -0,0 +1,78 @@
+package txutil
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+
+	"workshop/revert"
+)
+
+// Simulation is the result of a simulated transaction.
+type Simulation struct {
+	// ReturnData is the data returned by the eth_call.
+	ReturnData []byte
+
+	// Reverted is true if the transaction would revert. In that case
+	// RevertData contains the revert data, if provided by the node.
+	Reverted   bool
+	RevertData []byte
+
+	// Gas is the estimated gas usage, GasPrice is the current gas price and
+	// Fee is the expected transaction cost in wei. They are set only if the
+	// transaction would not revert.
+	Gas      uint64
+	GasPrice *big.Int
+	Fee      *big.Int
+}
+
+// Simulate executes the transaction using eth_call and eth_estimateGas
+// against the given block without signing or broadcasting it.
+//
+// If the transaction has no sender, from is used. A revert is not returned as
+// an error, it is reported in the Simulation instead.
+func Simulate(ctx context.Context, client rpc.RPC, tx types.Transaction, from types.Address, block types.BlockNumber) (*Simulation, error) {
+	call := tx.Call
+	if call.From == nil {
+		call.From = &from
+	}
+
+	sim := &Simulation{}
+	data, _, err := client.Call(ctx, call, block)
+	if err != nil {
+		if !isRevert(err) {
+			return nil, err
+		}
+		sim.Reverted = true
+		sim.RevertData, _ = revert.DataFromError(err)
+		return sim, nil
+	}
+	sim.ReturnData = data
+
+	// Gas fields must not limit the estimate.
+	call.GasLimit = nil
+	sim.Gas, err = client.EstimateGas(ctx, call, block)
+	if err != nil {
+		return nil, err
+	}
+	sim.GasPrice, err = client.GasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sim.Fee = new(big.Int).Mul(new(big.Int).SetUint64(sim.Gas), sim.GasPrice)
+	return sim, nil
+}
+
+// isRevert returns true if the RPC error was caused by a reverted execution.
+func isRevert(err error) bool {
+	if _, ok := revert.DataFromError(err); ok {
+		return true
+	}
+	var rpcErr *transport.RPCError
+	return errors.As(err, &rpcErr) && strings.Contains(strings.ToLower(rpcErr.Message), "revert")
+}
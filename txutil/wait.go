@@ -0,0 +1,148 @@
+// Package txutil provides helpers for sending and tracking transactions.
+package txutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+)
+
+// ErrDropped is returned by WaitForReceipt when the node no longer knows
+// about the transaction, either because it was dropped from the mempool or
+// because it was reorged out and not re-included.
+var ErrDropped = errors.New("txutil: transaction dropped")
+
+// RevertedError is returned by WaitForReceipt when the transaction was
+// mined, but its execution failed.
+type RevertedError struct {
+	Receipt *types.TransactionReceipt
+}
+
+// Error implements the error interface.
+func (e *RevertedError) Error() string {
+	return fmt.Sprintf(
+		"txutil: transaction %s reverted in block %s",
+		e.Receipt.TransactionHash.String(),
+		e.Receipt.BlockNumber.String(),
+	)
+}
+
+// droppedPolls is the number of consecutive polls in which the transaction
+// must be unknown to the node before it is considered dropped. Load-balanced
+// nodes may not see a just-sent transaction right away.
+const droppedPolls = 3
+
+// WaitOptions are the options for WaitForReceipt.
+type WaitOptions struct {
+	// Confirmations is the number of blocks, including the block with the
+	// transaction, that must be mined before the receipt is returned.
+	// Zero is treated as one.
+	Confirmations uint64
+
+	// Timeout is the maximum time to wait. Zero means no timeout.
+	Timeout time.Duration
+
+	// PollInterval is the time between polls. Defaults to 5 seconds.
+	PollInterval time.Duration
+}
+
+// WaitForReceipt waits until the transaction is mined with the required
+// number of confirmations and returns its receipt.
+//
+// If the transaction was reverted, the receipt is returned together with
+// a *RevertedError. If the transaction is removed from the canonical chain
+// by a reorg, the confirmation count starts over once it is included again.
+// If the node stops knowing about the transaction, ErrDropped is returned.
+func WaitForReceipt(ctx context.Context, client rpc.RPC, hash types.Hash, opts WaitOptions) (*types.TransactionReceipt, error) {
+	if opts.Confirmations == 0 {
+		opts.Confirmations = 1
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Timeout > 0 {
+		var ctxCancel context.CancelFunc
+		ctx, ctxCancel = context.WithTimeout(ctx, opts.Timeout)
+		defer ctxCancel()
+	}
+
+	missing := 0
+	for {
+		receipt, err := canonicalReceipt(ctx, client, hash)
+		if err != nil {
+			return nil, wrapCtxErr(ctx, hash, err)
+		}
+		if receipt != nil {
+			missing = 0
+			head, err := client.BlockNumber(ctx)
+			if err != nil {
+				return nil, wrapCtxErr(ctx, hash, err)
+			}
+			depth := new(big.Int).Sub(head, receipt.BlockNumber)
+			if depth.Sign() >= 0 && depth.Uint64()+1 >= opts.Confirmations {
+				if receipt.Status != nil && *receipt.Status == 0 {
+					return receipt, &RevertedError{Receipt: receipt}
+				}
+				return receipt, nil
+			}
+		} else {
+			// The transaction is either pending, or it was reorged out, or
+			// it was dropped.
+			tx, err := client.GetTransactionByHash(ctx, hash)
+			if err != nil {
+				return nil, wrapCtxErr(ctx, hash, err)
+			}
+			if tx.Hash == nil {
+				missing++
+				if missing >= droppedPolls {
+					return nil, ErrDropped
+				}
+			} else {
+				missing = 0
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, wrapCtxErr(ctx, hash, ctx.Err())
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// canonicalReceipt returns the transaction receipt if the transaction is
+// included in the canonical chain, or nil otherwise.
+func canonicalReceipt(ctx context.Context, client rpc.RPC, hash types.Hash) (*types.TransactionReceipt, error) {
+	receipt, err := client.GetTransactionReceipt(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.BlockHash == (types.Hash{}) || receipt.BlockNumber == nil {
+		return nil, nil
+	}
+	// A node may still return a receipt from a block that was just
+	// replaced by a reorg, so the block hash is checked against the
+	// canonical chain.
+	block, err := client.BlockByNumber(ctx, types.BlockNumberFromBigInt(receipt.BlockNumber), false)
+	if err != nil {
+		return nil, err
+	}
+	if block.Hash != receipt.BlockHash {
+		return nil, nil
+	}
+	return receipt, nil
+}
+
+// wrapCtxErr adds the transaction hash to errors caused by the context
+// cancellation or timeout.
+func wrapCtxErr(ctx context.Context, hash types.Hash, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("txutil: stopped waiting for transaction %s: %w", hash.String(), ctx.Err())
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+// Package uniswapv2 provides math and contract helpers for Uniswap V2 pairs.
+package uniswapv2
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+)
+
+// Methods of the Uniswap V2 factory, pairs and router.
+var (
+	GetPairMethod     = abi.MustParseMethod(`function getPair(address tokenA, address tokenB) external view returns (address pair)`)
+	GetReservesMethod = abi.MustParseMethod(`function getReserves() external view returns (uint112 reserve0, uint112 reserve1, uint32 blockTimestampLast)`)
+
+	SwapExactTokensForTokensMethod = abi.MustParseMethod(`
+		function swapExactTokensForTokens(
+			uint256 amountIn,
+			uint256 amountOutMin,
+			address[] path,
+			address to,
+			uint256 deadline
+		) external returns (uint256[] amounts)
+	`)
+
+	SwapTokensForExactTokensMethod = abi.MustParseMethod(`
+		function swapTokensForExactTokens(
+			uint256 amountOut,
+			uint256 amountInMax,
+			address[] path,
+			address to,
+			uint256 deadline
+		) external returns (uint256[] amounts)
+	`)
+)
+
+// ErrInsufficientLiquidity is returned by GetAmountIn when the pair does not
+// hold enough tokens to pay out the requested amount.
+var ErrInsufficientLiquidity = errors.New("uniswapv2: insufficient liquidity")
+
+// Deployment describes a deployment of Uniswap V2 or one of its forks.
+type Deployment struct {
+	Name string
+
+	// Factory is the address of the factory that deploys pairs with
+	// CREATE2 and implements getPair.
+	Factory types.Address
+
+	// InitCodeHash is the keccak256 hash of the pair creation code.
+	InitCodeHash types.Hash
+
+	// Router is the address of the router used to swap through pairs.
+	Router types.Address
+}
+
+// Uniswap is the Uniswap V2 deployment on Ethereum and Goerli.
+var Uniswap = Deployment{
+	Name:         "uniswap",
+	Factory:      types.MustAddressFromHex("0x5C69bEe701ef814a2B6a3EDD4B1652CB9cc5aA6f"),
+	InitCodeHash: types.MustHashFromHex("0x96e8ac4277198ff8b6f785478aa9a39f403cb768dd02cbee326c3e7da348845f", types.PadNone),
+	Router:       types.MustAddressFromHex("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D"),
+}
+
+// Deployments maps chain IDs to the known deployment on that chain.
+var Deployments = map[uint64]Deployment{
+	1: Uniswap, // Ethereum
+	5: Uniswap, // Goerli
+}
+
+// PairAddress computes the address of the pair for the tokens. The tokens
+// may be given in any order.
+func (d Deployment) PairAddress(tokenA, tokenB types.Address) types.Address {
+	token0, token1, _ := SortTokens(tokenA, tokenB)
+	salt := crypto.Keccak256(token0.Bytes(), token1.Bytes())
+	var b bytes.Buffer
+	b.WriteByte(0xff)
+	b.Write(d.Factory.Bytes())
+	b.Write(salt.Bytes())
+	b.Write(d.InitCodeHash.Bytes())
+	return types.MustAddressFromBytes(crypto.Keccak256(b.Bytes()).Bytes()[12:])
+}
+
+// SortTokens sorts the tokens the same way as pairs do.
+//
+// The inverted flag is true if tokenA is the token1 of the pair.
+func SortTokens(tokenA, tokenB types.Address) (token0, token1 types.Address, inverted bool) {
+	if bytes.Compare(tokenA.Bytes(), tokenB.Bytes()) > 0 {
+		return tokenB, tokenA, true
+	}
+	return tokenA, tokenB, false
+}
+
+// The swap fee is 0.3%, so 997/1000 of the input amount is traded.
+var (
+	feeNumerator   = big.NewInt(997)
+	feeDenominator = big.NewInt(1000)
+)
+
+// GetAmountOut returns the amount of tokens received for amountIn from a
+// pair with the given reserves, after the 0.3% fee is paid. It matches
+// UniswapV2Library.getAmountOut.
+func GetAmountOut(amountIn, reserveIn, reserveOut *big.Int) *big.Int {
+	if amountIn.Sign() <= 0 || reserveIn.Sign() <= 0 || reserveOut.Sign() <= 0 {
+		return new(big.Int)
+	}
+	amountInWithFee := new(big.Int).Mul(amountIn, feeNumerator)
+	num := new(big.Int).Mul(amountInWithFee, reserveOut)
+	den := new(big.Int).Mul(reserveIn, feeDenominator)
+	den.Add(den, amountInWithFee)
+	return num.Quo(num, den)
+}
+
+// GetAmountIn returns the amount of tokens that must be sold to receive
+// amountOut from a pair with the given reserves, including the 0.3% fee.
+// It matches UniswapV2Library.getAmountIn.
+func GetAmountIn(amountOut, reserveIn, reserveOut *big.Int) (*big.Int, error) {
+	if reserveIn.Sign() <= 0 || amountOut.Cmp(reserveOut) >= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	num := new(big.Int).Mul(reserveIn, amountOut)
+	num.Mul(num, feeDenominator)
+	den := new(big.Int).Sub(reserveOut, amountOut)
+	den.Mul(den, feeNumerator)
+	num.Quo(num, den)
+	return num.Add(num, big.NewInt(1)), nil
+}
+
+// Price returns the spot price of tokenIn expressed in tokenOut for a pair
+// with the given reserves, adjusted for the token decimals.
+func Price(reserveIn, reserveOut *big.Int, decimalsIn, decimalsOut uint8) *big.Rat {
+	if reserveIn.Sign() == 0 {
+		return new(big.Rat)
+	}
+	num := new(big.Int).Mul(reserveOut, pow10(decimalsIn))
+	den := new(big.Int).Mul(reserveIn, pow10(decimalsOut))
+	return new(big.Rat).SetFrac(num, den)
+}
+
+// pow10 returns 10^n.
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
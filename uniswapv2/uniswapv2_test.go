@@ -0,0 +1,175 @@
+package uniswapv2
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+var (
+	weth = types.MustAddressFromHex("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	usdc = types.MustAddressFromHex("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	dai  = types.MustAddressFromHex("0x6B175474E89094C44Da98b954EedeAC495271d0F")
+)
+
+// bigInt parses a decimal integer.
+func bigInt(s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid integer: " + s)
+	}
+	return x
+}
+
+func TestPairAddress(t *testing.T) {
+	tests := []struct {
+		name           string
+		tokenA, tokenB types.Address
+		pair           types.Address
+	}{
+		{
+			name:   "WETH/USDC",
+			tokenA: weth,
+			tokenB: usdc,
+			pair:   types.MustAddressFromHex("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"),
+		},
+		{
+			name:   "USDC/WETH",
+			tokenA: usdc,
+			tokenB: weth,
+			pair:   types.MustAddressFromHex("0xB4e16d0168e52d35CaCD2c6185b44281Ec28C9Dc"),
+		},
+		{
+			name:   "DAI/WETH",
+			tokenA: dai,
+			tokenB: weth,
+			pair:   types.MustAddressFromHex("0xA478c2975Ab1Ea89e8196811F51A7B7Ade33eB11"),
+		},
+	}
+	for _, tt := range tests {
+		if got := Uniswap.PairAddress(tt.tokenA, tt.tokenB); got != tt.pair {
+			t.Errorf("%s: got %s, expected %s", tt.name, got, tt.pair)
+		}
+	}
+}
+
+func TestSortTokens(t *testing.T) {
+	token0, token1, inverted := SortTokens(weth, usdc)
+	if token0 != usdc || token1 != weth || !inverted {
+		t.Errorf("got %s, %s, %t, expected %s, %s, true", token0, token1, inverted, usdc, weth)
+	}
+	token0, token1, inverted = SortTokens(usdc, weth)
+	if token0 != usdc || token1 != weth || inverted {
+		t.Errorf("got %s, %s, %t, expected %s, %s, false", token0, token1, inverted, usdc, weth)
+	}
+}
+
+// The test cases are taken from the UniswapV2Library and UniswapV2Router02
+// tests of the Uniswap V2 periphery repository.
+func TestGetAmountOut(t *testing.T) {
+	tests := []struct {
+		amountIn, reserveIn, reserveOut *big.Int
+		amountOut                       *big.Int
+	}{
+		{amountIn: big.NewInt(2), reserveIn: big.NewInt(100), reserveOut: big.NewInt(100), amountOut: big.NewInt(1)},
+		{amountIn: big.NewInt(1e18), reserveIn: big.NewInt(5e18), reserveOut: bigInt("10000000000000000000"), amountOut: big.NewInt(1662497915624478906)},
+		{amountIn: big.NewInt(0), reserveIn: big.NewInt(100), reserveOut: big.NewInt(100), amountOut: big.NewInt(0)},
+		{amountIn: big.NewInt(2), reserveIn: big.NewInt(0), reserveOut: big.NewInt(100), amountOut: big.NewInt(0)},
+		{amountIn: big.NewInt(2), reserveIn: big.NewInt(100), reserveOut: big.NewInt(0), amountOut: big.NewInt(0)},
+	}
+	for _, tt := range tests {
+		if got := GetAmountOut(tt.amountIn, tt.reserveIn, tt.reserveOut); got.Cmp(tt.amountOut) != 0 {
+			t.Errorf("GetAmountOut(%s, %s, %s) = %s, expected %s", tt.amountIn, tt.reserveIn, tt.reserveOut, got, tt.amountOut)
+		}
+	}
+}
+
+func TestGetAmountIn(t *testing.T) {
+	tests := []struct {
+		amountOut, reserveIn, reserveOut *big.Int
+		amountIn                         *big.Int
+	}{
+		{amountOut: big.NewInt(1), reserveIn: big.NewInt(100), reserveOut: big.NewInt(100), amountIn: big.NewInt(2)},
+		{amountOut: big.NewInt(1e18), reserveIn: big.NewInt(5e18), reserveOut: bigInt("10000000000000000000"), amountIn: big.NewInt(557227237267357629)},
+	}
+	for _, tt := range tests {
+		got, err := GetAmountIn(tt.amountOut, tt.reserveIn, tt.reserveOut)
+		if err != nil {
+			t.Fatalf("GetAmountIn(%s, %s, %s): %v", tt.amountOut, tt.reserveIn, tt.reserveOut, err)
+		}
+		if got.Cmp(tt.amountIn) != 0 {
+			t.Errorf("GetAmountIn(%s, %s, %s) = %s, expected %s", tt.amountOut, tt.reserveIn, tt.reserveOut, got, tt.amountIn)
+		}
+
+		// Selling the returned amount yields at least the requested amount.
+		if out := GetAmountOut(got, tt.reserveIn, tt.reserveOut); out.Cmp(tt.amountOut) < 0 {
+			t.Errorf("GetAmountOut(%s) = %s, expected at least %s", got, out, tt.amountOut)
+		}
+	}
+}
+
+func TestGetAmountInInsufficientLiquidity(t *testing.T) {
+	tests := []struct {
+		name                             string
+		amountOut, reserveIn, reserveOut *big.Int
+	}{
+		{name: "amount out equal to the reserve", amountOut: big.NewInt(100), reserveIn: big.NewInt(100), reserveOut: big.NewInt(100)},
+		{name: "amount out above the reserve", amountOut: big.NewInt(101), reserveIn: big.NewInt(100), reserveOut: big.NewInt(100)},
+		{name: "empty input reserve", amountOut: big.NewInt(1), reserveIn: big.NewInt(0), reserveOut: big.NewInt(100)},
+	}
+	for _, tt := range tests {
+		if _, err := GetAmountIn(tt.amountOut, tt.reserveIn, tt.reserveOut); !errors.Is(err, ErrInsufficientLiquidity) {
+			t.Errorf("%s: expected ErrInsufficientLiquidity, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		name                    string
+		reserveIn, reserveOut   *big.Int
+		decimalsIn, decimalsOut uint8
+		expected                *big.Rat
+	}{
+		{
+			name:        "same decimals",
+			reserveIn:   big.NewInt(5e18),
+			reserveOut:  bigInt("10000000000000000000"),
+			decimalsIn:  18,
+			decimalsOut: 18,
+			expected:    big.NewRat(2, 1),
+		},
+		{
+			// 1000 WETH and 2,000,000 USDC.
+			name:        "WETH in USDC",
+			reserveIn:   bigInt("1000000000000000000000"),
+			reserveOut:  big.NewInt(2_000_000e6),
+			decimalsIn:  18,
+			decimalsOut: 6,
+			expected:    big.NewRat(2000, 1),
+		},
+		{
+			name:        "USDC in WETH",
+			reserveIn:   big.NewInt(2_000_000e6),
+			reserveOut:  bigInt("1000000000000000000000"),
+			decimalsIn:  6,
+			decimalsOut: 18,
+			expected:    big.NewRat(1, 2000),
+		},
+		{
+			name:        "empty reserve",
+			reserveIn:   big.NewInt(0),
+			reserveOut:  big.NewInt(100),
+			decimalsIn:  18,
+			decimalsOut: 18,
+			expected:    new(big.Rat),
+		},
+	}
+	for _, tt := range tests {
+		if got := Price(tt.reserveIn, tt.reserveOut, tt.decimalsIn, tt.decimalsOut); got.Cmp(tt.expected) != 0 {
+			t.Errorf("%s: got %s, expected %s", tt.name, got.RatString(), tt.expected.RatString())
+		}
+	}
+}
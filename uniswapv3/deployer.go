@@ -0,0 +1,169 @@
+package uniswapv3
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+)
+
+// PoolDeployer describes a deployment of Uniswap V3 or one of its forks.
+//
+// Pools are deployed with CREATE2, so their addresses can be computed from
+// the deployer address, the init code hash and the pool parameters without
+// calling any contract.
+type PoolDeployer struct {
+	Name string
+
+	// Factory is the address of the factory that creates pools and
+	// implements getPool.
+	Factory types.Address
+
+	// Deployer is the address that deploys pools with CREATE2. It is the
+	// factory itself for Uniswap V3 and most forks, but PancakeSwap V3 uses
+	// a separate deployer contract.
+	Deployer types.Address
+
+	// InitCodeHash is the keccak256 hash of the pool creation code.
+	InitCodeHash types.Hash
+
+	// FeeTiers are the fee tiers enabled in the factory, in hundredths of a
+	// bip.
+	FeeTiers []uint32
+
+	// Quoter is the address of the QuoterV2 contract of the deployment.
+	Quoter types.Address
+
+	// SwapRouter is the address of the SwapRouter02 contract of the
+	// deployment, or a router with the same interface. It is zero if no
+	// such router is known.
+	SwapRouter types.Address
+}
+
+// SupportsFee returns true if pools with the given fee tier can be created
+// by the deployment.
+func (d PoolDeployer) SupportsFee(fee uint32) bool {
+	for _, tier := range d.FeeTiers {
+		if tier == fee {
+			return true
+		}
+	}
+	return false
+}
+
+// PoolAddress computes the address of the pool for the token pair and fee
+// tier. The tokens may be given in any order.
+func (d PoolDeployer) PoolAddress(tokenA, tokenB types.Address, fee uint32) types.Address {
+	token0, token1, _ := SortTokens(tokenA, tokenB)
+	salt := crypto.Keccak256(
+		types.MustHashFromBytes(token0.Bytes(), types.PadLeft).Bytes(),
+		types.MustHashFromBytes(token1.Bytes(), types.PadLeft).Bytes(),
+		types.MustHashFromBigInt(big.NewInt(int64(fee))).Bytes(),
+	)
+	var b bytes.Buffer
+	b.WriteByte(0xff)
+	b.Write(d.Deployer.Bytes())
+	b.Write(salt.Bytes())
+	b.Write(d.InitCodeHash.Bytes())
+	return types.MustAddressFromBytes(crypto.Keccak256(b.Bytes()).Bytes()[12:])
+}
+
+// SortTokens sorts the tokens the same way as pools do.
+//
+// The inverted flag is true if tokenA is the token1 of the pool.
+func SortTokens(tokenA, tokenB types.Address) (token0, token1 types.Address, inverted bool) {
+	if bytes.Compare(tokenA.Bytes(), tokenB.Bytes()) > 0 {
+		return tokenB, tokenA, true
+	}
+	return tokenA, tokenB, false
+}
+
+// Known deployments.
+var (
+	uniswapInitCodeHash     = types.MustHashFromHex("0xe34f199b19b2b4f47f68442619d555527d244f78a3297ea89325f843f87b8b54", types.PadNone)
+	pancakeSwapInitCodeHash = types.MustHashFromHex("0x6ce8eb472fa82df5469c6ab6d485f17c3ad13c8cd7af59b3d4a8026c5ce0f7e2", types.PadNone)
+
+	uniswapFeeTiers     = []uint32{100, 500, 3000, 10000}
+	pancakeSwapFeeTiers = []uint32{100, 500, 2500, 10000}
+
+	// Uniswap is the Uniswap V3 deployment used on Ethereum, its testnets
+	// and most L2 networks.
+	Uniswap = PoolDeployer{
+		Name:         "uniswap",
+		Factory:      types.MustAddressFromHex("0x1F98431c8aD98523631AE4a59f267346ea31F984"),
+		Deployer:     types.MustAddressFromHex("0x1F98431c8aD98523631AE4a59f267346ea31F984"),
+		InitCodeHash: uniswapInitCodeHash,
+		FeeTiers:     uniswapFeeTiers,
+		Quoter:       types.MustAddressFromHex("0x61fFE014bA17989E743c5F6cB21bF9697530B21e"),
+		SwapRouter:   types.MustAddressFromHex("0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45"),
+	}
+
+	// UniswapBase is the Uniswap V3 deployment on Base.
+	UniswapBase = PoolDeployer{
+		Name:         "uniswap",
+		Factory:      types.MustAddressFromHex("0x33128a8fC17869897dcE68Ed026d694621f6FDfD"),
+		Deployer:     types.MustAddressFromHex("0x33128a8fC17869897dcE68Ed026d694621f6FDfD"),
+		InitCodeHash: uniswapInitCodeHash,
+		FeeTiers:     uniswapFeeTiers,
+		Quoter:       types.MustAddressFromHex("0x3d4e44Eb1374240CE5F1B871ab261CD16335B76a"),
+		SwapRouter:   types.MustAddressFromHex("0x2626664c2603336E57B271c5C0b26F421741e481"),
+	}
+
+	// SushiSwap is the SushiSwap V3 deployment on Ethereum. SushiSwap V3
+	// uses the Uniswap V3 pool code, so the init code hash is the same.
+	SushiSwap = PoolDeployer{
+		Name:         "sushiswap",
+		Factory:      types.MustAddressFromHex("0xbACEB8eC6b9355Dfc0269C18bac9d6E2Bdc29C4F"),
+		Deployer:     types.MustAddressFromHex("0xbACEB8eC6b9355Dfc0269C18bac9d6E2Bdc29C4F"),
+		InitCodeHash: uniswapInitCodeHash,
+		FeeTiers:     uniswapFeeTiers,
+		Quoter:       types.MustAddressFromHex("0x64e8802FE490fa7cc61d3463958199161Bb608A7"),
+	}
+
+	// SushiSwapArbitrum is the SushiSwap V3 deployment on Arbitrum One.
+	SushiSwapArbitrum = PoolDeployer{
+		Name:         "sushiswap",
+		Factory:      types.MustAddressFromHex("0x1af415a1EbA07a4986a52B6f2e7dE7003D82231e"),
+		Deployer:     types.MustAddressFromHex("0x1af415a1EbA07a4986a52B6f2e7dE7003D82231e"),
+		InitCodeHash: uniswapInitCodeHash,
+		FeeTiers:     uniswapFeeTiers,
+		Quoter:       types.MustAddressFromHex("0x0524E833cCD057e4d7A296e3aaAb9f7675964Ce1"),
+	}
+
+	// PancakeSwap is the PancakeSwap V3 deployment. It uses the same
+	// addresses on all supported networks. Its SmartRouter implements the
+	// SwapRouter02 interface.
+	PancakeSwap = PoolDeployer{
+		Name:         "pancakeswap",
+		Factory:      types.MustAddressFromHex("0x0BFbCF9fa4f9C56B0F40a671Ad40E0805A091865"),
+		Deployer:     types.MustAddressFromHex("0x41ff9AA7e16B8B1a8a8dc4f0eFacd93D02d071c9"),
+		InitCodeHash: pancakeSwapInitCodeHash,
+		FeeTiers:     pancakeSwapFeeTiers,
+		Quoter:       types.MustAddressFromHex("0xB048Bbc1Ee6b733FFfCFb9e9CeF7375518e25997"),
+		SwapRouter:   types.MustAddressFromHex("0x13f4EA83D0bd40E75C8222255bc855a974568Dd4"),
+	}
+)
+
+// Deployments maps chain IDs to the known deployments on that chain.
+var Deployments = map[uint64][]PoolDeployer{
+	1:     {Uniswap, SushiSwap, PancakeSwap},         // Ethereum
+	5:     {Uniswap},                                 // Goerli
+	10:    {Uniswap},                                 // Optimism
+	56:    {PancakeSwap},                             // BNB Smart Chain
+	137:   {Uniswap},                                 // Polygon
+	8453:  {UniswapBase, PancakeSwap},                // Base
+	42161: {Uniswap, SushiSwapArbitrum, PancakeSwap}, // Arbitrum One
+}
+
+// FindDeployer returns the deployment with the given name on the chain. The
+// name is case-insensitive.
+func FindDeployer(chainID uint64, name string) (PoolDeployer, bool) {
+	for _, d := range Deployments[chainID] {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return PoolDeployer{}, false
+}
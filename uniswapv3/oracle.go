@@ -0,0 +1,16 @@
+package uniswapv3
+
+// This file is a port of the consult function of the Uniswap V3
+// OracleLibrary.
+
+// ArithmeticMeanTick returns the time-weighted average tick over a window
+// from the tick accumulator values observed at its start and end. The
+// result is rounded towards negative infinity, like in the OracleLibrary.
+func ArithmeticMeanTick(tickCumulativeStart, tickCumulativeEnd int64, window uint32) int {
+	delta := tickCumulativeEnd - tickCumulativeStart
+	tick := delta / int64(window)
+	if delta < 0 && delta%int64(window) != 0 {
+		tick--
+	}
+	return int(tick)
+}
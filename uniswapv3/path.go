@@ -0,0 +1,23 @@
+package uniswapv3
+
+import (
+	"errors"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+// EncodePath encodes a multi-hop swap path for the exactInput method of the
+// swap routers. The path is the packed sequence of token addresses with the
+// 3-byte fee tier of the pool between each pair of tokens, so there must be
+// exactly one fee less than tokens.
+func EncodePath(tokens []types.Address, fees []uint32) ([]byte, error) {
+	if len(tokens) < 2 || len(fees) != len(tokens)-1 {
+		return nil, errors.New("uniswapv3: path must have one fee less than tokens")
+	}
+	path := make([]byte, 0, len(tokens)*types.AddressLength+len(fees)*3)
+	for i, fee := range fees {
+		path = append(path, tokens[i].Bytes()...)
+		path = append(path, byte(fee>>16), byte(fee>>8), byte(fee))
+	}
+	return append(path, tokens[len(tokens)-1].Bytes()...), nil
+}
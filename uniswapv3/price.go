@@ -0,0 +1,46 @@
+// Package uniswapv3 provides math and contract helpers for Uniswap V3 pools.
+package uniswapv3
+
+import (
+	"math/big"
+)
+
+// Q96 is 2^96, the fixed point scale of sqrt prices.
+var Q96 = new(big.Int).Lsh(big.NewInt(1), 96)
+
+// q192 is 2^192, the fixed point scale of squared sqrt prices.
+var q192 = new(big.Int).Lsh(big.NewInt(1), 192)
+
+// SqrtPriceX96ToPrice converts a sqrtPriceX96 value to the exact price of
+// token0 expressed in token1, adjusted for the token decimals.
+func SqrtPriceX96ToPrice(sqrtPriceX96 *big.Int, token0Decimals, token1Decimals uint8) *big.Rat {
+	// price = sqrtPriceX96^2 / 2^192 * 10^token0Decimals / 10^token1Decimals
+	num := new(big.Int).Mul(sqrtPriceX96, sqrtPriceX96)
+	num.Mul(num, pow10(token0Decimals))
+	den := new(big.Int).Mul(q192, pow10(token1Decimals))
+	return new(big.Rat).SetFrac(num, den)
+}
+
+// PriceToSqrtPriceX96 converts the price of token0 expressed in token1,
+// adjusted for the token decimals, to a sqrtPriceX96 value. The result is
+// rounded down.
+//
+// For any sqrtPriceX96, PriceToSqrtPriceX96(SqrtPriceX96ToPrice(x)) == x.
+func PriceToSqrtPriceX96(price *big.Rat, token0Decimals, token1Decimals uint8) *big.Int {
+	// sqrtPriceX96 = sqrt(price * 10^token1Decimals / 10^token0Decimals * 2^192)
+	num := new(big.Int).Mul(price.Num(), pow10(token1Decimals))
+	num.Mul(num, q192)
+	den := new(big.Int).Mul(price.Denom(), pow10(token0Decimals))
+	return num.Quo(num, den).Sqrt(num)
+}
+
+// FormatPrice formats a price as a decimal number with the given number of
+// digits after the decimal point. The last digit is rounded.
+func FormatPrice(price *big.Rat, precision int) string {
+	return price.FloatString(precision)
+}
+
+// pow10 returns 10^n.
+func pow10(n uint8) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,89 @@
+package uniswapv3
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPriceRoundTrip(t *testing.T) {
+	// Every tick in the range is checked, which takes a while, so only a
+	// sample of them is checked in the short mode.
+	step := 1
+	if testing.Short() {
+		step = 97
+	}
+	decimals := []struct {
+		name           string
+		token0, token1 uint8
+	}{
+		{name: "18/18", token0: 18, token1: 18},
+		{name: "18/6", token0: 18, token1: 6},
+	}
+	for _, d := range decimals {
+		d := d
+		t.Run(d.name, func(t *testing.T) {
+			t.Parallel()
+			for tick := MinTick; tick <= MaxTick; tick += step {
+				if tick+step > MaxTick {
+					// Always check the last tick of the range.
+					tick = MaxTick
+				}
+				sqrtPriceX96, err := GetSqrtRatioAtTick(tick)
+				if err != nil {
+					t.Fatalf("GetSqrtRatioAtTick(%d): %v", tick, err)
+				}
+				price := SqrtPriceX96ToPrice(sqrtPriceX96, d.token0, d.token1)
+				if got := PriceToSqrtPriceX96(price, d.token0, d.token1); got.Cmp(sqrtPriceX96) != 0 {
+					t.Fatalf("tick %d: round trip returned %s, expected %s", tick, got, sqrtPriceX96)
+				}
+			}
+		})
+	}
+}
+
+func TestSqrtPriceX96ToPrice(t *testing.T) {
+	tests := []struct {
+		name           string
+		sqrtPriceX96   *big.Int
+		token0, token1 uint8
+		price          *big.Rat
+	}{
+		{
+			name:         "one",
+			sqrtPriceX96: Q96,
+			token0:       18,
+			token1:       18,
+			price:        big.NewRat(1, 1),
+		},
+		{
+			name:         "four",
+			sqrtPriceX96: new(big.Int).Lsh(Q96, 1),
+			token0:       18,
+			token1:       18,
+			price:        big.NewRat(4, 1),
+		},
+		{
+			// A raw price of one is 1e12 for a token with 18 decimals
+			// expressed in a token with 6 decimals.
+			name:         "18/6 decimals",
+			sqrtPriceX96: Q96,
+			token0:       18,
+			token1:       6,
+			price:        big.NewRat(1e12, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price := SqrtPriceX96ToPrice(tt.sqrtPriceX96, tt.token0, tt.token1)
+			if price.Cmp(tt.price) != 0 {
+				t.Fatalf("expected price %s, got %s", tt.price.RatString(), price.RatString())
+			}
+		})
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	if got := FormatPrice(big.NewRat(2, 3), 4); got != "0.6667" {
+		t.Fatalf("expected 0.6667, got %s", got)
+	}
+}
@@ -0,0 +1,109 @@
+package uniswapv3
+
+import (
+	"math/big"
+)
+
+// Quote is the estimated result of an exact input swap through a pool.
+type Quote struct {
+	// SqrtPriceX96 is the sqrt price before the swap.
+	SqrtPriceX96 *big.Int
+
+	// SqrtPriceAfterX96 is the estimated sqrt price after the swap.
+	SqrtPriceAfterX96 *big.Int
+
+	// AmountIn is the amount of tokens sent to the pool, excluding the fee.
+	AmountIn *big.Int
+
+	// AmountOut is the estimated amount of tokens received from the pool.
+	AmountOut *big.Int
+
+	// FeeAmount is the fee paid in the input token.
+	FeeAmount *big.Int
+
+	// CrossesTick is true if the swap moves the price out of the current
+	// tick spacing range. The liquidity of the pool may change at the
+	// range boundary, so the estimate is only approximate in that case.
+	CrossesTick bool
+
+	zeroForOne bool
+}
+
+// QuoteExactInput estimates the result of selling amountIn of token0
+// (zeroForOne) or token1 through a pool with the given state.
+//
+// The in-range liquidity is assumed to be constant, which holds as long as
+// the price stays within the tick spacing range of the current tick. If the
+// swap leaves that range, the quote is still computed with the current
+// liquidity and CrossesTick is set.
+func QuoteExactInput(sqrtPriceX96 *big.Int, tick, tickSpacing int, liquidity, amountIn *big.Int, fee uint32, zeroForOne bool) (*Quote, error) {
+	if liquidity.Sign() <= 0 {
+		return nil, ErrInvalidLiquidity
+	}
+
+	// Ticks can be initialized only at multiples of the tick spacing, so the
+	// liquidity cannot change before the price reaches the nearest multiple
+	// in the swap direction.
+	var boundary int
+	if zeroForOne {
+		boundary = floorDiv(tick, tickSpacing) * tickSpacing
+	} else {
+		boundary = (floorDiv(tick, tickSpacing) + 1) * tickSpacing
+	}
+	if boundary < MinTick {
+		boundary = MinTick
+	}
+	if boundary > MaxTick {
+		boundary = MaxTick
+	}
+	sqrtPriceBoundaryX96, err := GetSqrtRatioAtTick(boundary)
+	if err != nil {
+		return nil, err
+	}
+
+	// Swap towards the price limit as if the liquidity was constant.
+	var sqrtPriceTargetX96 *big.Int
+	if zeroForOne {
+		sqrtPriceTargetX96 = new(big.Int).Add(MinSqrtRatio, big.NewInt(1))
+	} else {
+		sqrtPriceTargetX96 = new(big.Int).Sub(MaxSqrtRatio, big.NewInt(1))
+	}
+	step, err := ComputeSwapStep(sqrtPriceX96, sqrtPriceTargetX96, liquidity, amountIn, fee)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Quote{
+		SqrtPriceX96:      new(big.Int).Set(sqrtPriceX96),
+		SqrtPriceAfterX96: step.SqrtPriceNextX96,
+		AmountIn:          step.AmountIn,
+		AmountOut:         step.AmountOut,
+		FeeAmount:         step.FeeAmount,
+		zeroForOne:        zeroForOne,
+	}
+	if zeroForOne {
+		q.CrossesTick = step.SqrtPriceNextX96.Cmp(sqrtPriceBoundaryX96) < 0
+	} else {
+		q.CrossesTick = step.SqrtPriceNextX96.Cmp(sqrtPriceBoundaryX96) > 0
+	}
+	return q, nil
+}
+
+// PriceImpact returns the relative difference between the amount received
+// at the spot price and the quoted amount, both after the fee is paid. The
+// result is a fraction, for example 0.01 for a 1% price impact.
+func (q *Quote) PriceImpact() *big.Rat {
+	// The price of token0 in token1 is sqrtPriceX96^2 / 2^192.
+	num := new(big.Int).Mul(q.SqrtPriceX96, q.SqrtPriceX96)
+	den := new(big.Int).Set(q192)
+	if !q.zeroForOne {
+		num, den = den, num
+	}
+	spotOut := new(big.Rat).SetFrac(num.Mul(num, q.AmountIn), den)
+	if spotOut.Sign() == 0 {
+		return new(big.Rat)
+	}
+	impact := new(big.Rat).SetInt(q.AmountOut)
+	impact.Quo(impact, spotOut)
+	return impact.Sub(big.NewRat(1, 1), impact)
+}
@@ -0,0 +1,105 @@
+package uniswapv3
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestQuoteExactInputCrossesTick(t *testing.T) {
+	const (
+		tick        = 30
+		tickSpacing = 60
+		fee         = 3000
+	)
+	var (
+		liquidity = big.NewInt(1e18)
+		lower, _  = GetSqrtRatioAtTick(0)
+		upper, _  = GetSqrtRatioAtTick(tickSpacing)
+		sqrtP, _  = GetSqrtRatioAtTick(tick)
+	)
+	// grossAmount returns the input amount, including the fee, that moves the
+	// price by exactly the given amount.
+	grossAmount := func(amount *big.Int) *big.Int {
+		return mulDivRoundingUp(amount, feeDenominator, big.NewInt(1e6-fee))
+	}
+	tests := []struct {
+		name       string
+		zeroForOne bool
+		amountIn   *big.Int
+		crosses    bool
+	}{
+		{
+			name:       "zeroForOne within the range",
+			zeroForOne: true,
+			amountIn:   new(big.Int).Quo(grossAmount(GetAmount0Delta(lower, sqrtP, liquidity, true)), big.NewInt(2)),
+			crosses:    false,
+		},
+		{
+			name:       "zeroForOne past the lower boundary",
+			zeroForOne: true,
+			amountIn:   new(big.Int).Mul(grossAmount(GetAmount0Delta(lower, sqrtP, liquidity, true)), big.NewInt(2)),
+			crosses:    true,
+		},
+		{
+			name:       "oneForZero within the range",
+			zeroForOne: false,
+			amountIn:   new(big.Int).Quo(grossAmount(GetAmount1Delta(sqrtP, upper, liquidity, true)), big.NewInt(2)),
+			crosses:    false,
+		},
+		{
+			name:       "oneForZero past the upper boundary",
+			zeroForOne: false,
+			amountIn:   new(big.Int).Mul(grossAmount(GetAmount1Delta(sqrtP, upper, liquidity, true)), big.NewInt(2)),
+			crosses:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := QuoteExactInput(sqrtP, tick, tickSpacing, liquidity, tt.amountIn, fee, tt.zeroForOne)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.CrossesTick != tt.crosses {
+				t.Fatalf("expected CrossesTick %t, got %t", tt.crosses, q.CrossesTick)
+			}
+			if total := new(big.Int).Add(q.AmountIn, q.FeeAmount); total.Cmp(tt.amountIn) != 0 {
+				t.Fatalf("expected amount in with fee %s, got %s", tt.amountIn, total)
+			}
+		})
+	}
+}
+
+func TestQuoteExactInputWithoutLiquidity(t *testing.T) {
+	_, err := QuoteExactInput(Q96, 0, 60, big.NewInt(0), big.NewInt(1e18), 3000, true)
+	if !errors.Is(err, ErrInvalidLiquidity) {
+		t.Fatalf("expected ErrInvalidLiquidity, got %v", err)
+	}
+}
+
+func TestQuotePriceImpact(t *testing.T) {
+	liquidity := bigInt("1000000000000000000000")
+	small, err := QuoteExactInput(Q96, 0, 60, liquidity, big.NewInt(1e15), 3000, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := QuoteExactInput(Q96, 0, 60, liquidity, bigInt("100000000000000000000"), 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallImpact, largeImpact := small.PriceImpact(), large.PriceImpact()
+	if smallImpact.Sign() <= 0 || smallImpact.Cmp(big.NewRat(1, 1e5)) > 0 {
+		t.Fatalf("expected a small positive price impact, got %s", smallImpact.FloatString(8))
+	}
+	if largeImpact.Cmp(smallImpact) <= 0 {
+		t.Fatalf("expected the price impact of a larger swap to be higher, got %s", largeImpact.FloatString(8))
+	}
+
+	// Without the fee, selling 0.1 of the virtual reserves of token0 at
+	// price 1 moves the price to 1/1.1^2, so the impact is 1 - 1/1.1.
+	expected := big.NewRat(1, 11)
+	diff := new(big.Rat).Sub(largeImpact, expected)
+	if diff.Abs(diff).Cmp(big.NewRat(1, 1e9)) > 0 {
+		t.Fatalf("expected price impact %s, got %s", expected.FloatString(8), largeImpact.FloatString(8))
+	}
+}
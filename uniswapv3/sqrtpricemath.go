@@ -0,0 +1,152 @@
+package uniswapv3
+
+import (
+	"errors"
+	"math/big"
+)
+
+// This file is a port of the Uniswap V3 SqrtPriceMath and FullMath
+// libraries. Intermediate results that would overflow in Solidity take the
+// same fallback paths, so the results match the Solidity implementation bit
+// for bit.
+
+var (
+	// ErrInvalidLiquidity is returned when a price is moved through a
+	// range without liquidity.
+	ErrInvalidLiquidity = errors.New("uniswapv3: liquidity must be positive")
+
+	// ErrInsufficientLiquidity is returned when the requested output amount
+	// is higher than the amount held by the liquidity range.
+	ErrInsufficientLiquidity = errors.New("uniswapv3: insufficient liquidity")
+)
+
+var (
+	q256       = new(big.Int).Lsh(big.NewInt(1), 256)
+	maxUint160 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 160), big.NewInt(1))
+)
+
+// GetAmount0Delta returns the amount of token0 between two sqrt prices for
+// the given liquidity: liquidity / sqrtA - liquidity / sqrtB.
+func GetAmount0Delta(sqrtRatioAX96, sqrtRatioBX96, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtRatioAX96.Cmp(sqrtRatioBX96) > 0 {
+		sqrtRatioAX96, sqrtRatioBX96 = sqrtRatioBX96, sqrtRatioAX96
+	}
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	numerator2 := new(big.Int).Sub(sqrtRatioBX96, sqrtRatioAX96)
+	if roundUp {
+		return divRoundingUp(mulDivRoundingUp(numerator1, numerator2, sqrtRatioBX96), sqrtRatioAX96)
+	}
+	amount := mulDiv(numerator1, numerator2, sqrtRatioBX96)
+	return amount.Quo(amount, sqrtRatioAX96)
+}
+
+// GetAmount1Delta returns the amount of token1 between two sqrt prices for
+// the given liquidity: liquidity * (sqrtB - sqrtA).
+func GetAmount1Delta(sqrtRatioAX96, sqrtRatioBX96, liquidity *big.Int, roundUp bool) *big.Int {
+	if sqrtRatioAX96.Cmp(sqrtRatioBX96) > 0 {
+		sqrtRatioAX96, sqrtRatioBX96 = sqrtRatioBX96, sqrtRatioAX96
+	}
+	diff := new(big.Int).Sub(sqrtRatioBX96, sqrtRatioAX96)
+	if roundUp {
+		return mulDivRoundingUp(liquidity, diff, Q96)
+	}
+	return mulDiv(liquidity, diff, Q96)
+}
+
+// GetNextSqrtPriceFromInput returns the sqrt price after adding amountIn of
+// token0 (zeroForOne) or token1 to a liquidity range. The price is rounded
+// so that the pool receives at least amountIn.
+func GetNextSqrtPriceFromInput(sqrtPriceX96, liquidity, amountIn *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPriceX96.Sign() <= 0 {
+		return nil, ErrSqrtRatioOutOfRange
+	}
+	if liquidity.Sign() <= 0 {
+		return nil, ErrInvalidLiquidity
+	}
+	if zeroForOne {
+		return nextSqrtPriceFromAmount0RoundingUp(sqrtPriceX96, liquidity, amountIn, true)
+	}
+	return nextSqrtPriceFromAmount1RoundingDown(sqrtPriceX96, liquidity, amountIn, true)
+}
+
+// GetNextSqrtPriceFromOutput returns the sqrt price after removing
+// amountOut of token1 (zeroForOne) or token0 from a liquidity range. The
+// price is rounded so that the pool pays out at most amountOut.
+func GetNextSqrtPriceFromOutput(sqrtPriceX96, liquidity, amountOut *big.Int, zeroForOne bool) (*big.Int, error) {
+	if sqrtPriceX96.Sign() <= 0 {
+		return nil, ErrSqrtRatioOutOfRange
+	}
+	if liquidity.Sign() <= 0 {
+		return nil, ErrInvalidLiquidity
+	}
+	if zeroForOne {
+		return nextSqrtPriceFromAmount1RoundingDown(sqrtPriceX96, liquidity, amountOut, false)
+	}
+	return nextSqrtPriceFromAmount0RoundingUp(sqrtPriceX96, liquidity, amountOut, false)
+}
+
+func nextSqrtPriceFromAmount0RoundingUp(sqrtPriceX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	if amount.Sign() == 0 {
+		return new(big.Int).Set(sqrtPriceX96), nil
+	}
+	numerator1 := new(big.Int).Lsh(liquidity, 96)
+	product := new(big.Int).Mul(amount, sqrtPriceX96)
+	if add {
+		if product.Cmp(q256) < 0 {
+			denominator := new(big.Int).Add(numerator1, product)
+			if denominator.Cmp(q256) < 0 {
+				return mulDivRoundingUp(numerator1, sqrtPriceX96, denominator), nil
+			}
+		}
+		// The product overflows, so the price is computed as
+		// liquidity / (liquidity / sqrtP + amount).
+		denominator := new(big.Int).Quo(numerator1, sqrtPriceX96)
+		denominator.Add(denominator, amount)
+		return divRoundingUp(numerator1, denominator), nil
+	}
+	if product.Cmp(q256) >= 0 || numerator1.Cmp(product) <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	denominator := new(big.Int).Sub(numerator1, product)
+	next := mulDivRoundingUp(numerator1, sqrtPriceX96, denominator)
+	if next.Cmp(maxUint160) > 0 {
+		return nil, ErrSqrtRatioOutOfRange
+	}
+	return next, nil
+}
+
+func nextSqrtPriceFromAmount1RoundingDown(sqrtPriceX96, liquidity, amount *big.Int, add bool) (*big.Int, error) {
+	if add {
+		next := mulDiv(amount, Q96, liquidity)
+		next.Add(next, sqrtPriceX96)
+		if next.Cmp(maxUint160) > 0 {
+			return nil, ErrSqrtRatioOutOfRange
+		}
+		return next, nil
+	}
+	quotient := mulDivRoundingUp(amount, Q96, liquidity)
+	if sqrtPriceX96.Cmp(quotient) <= 0 {
+		return nil, ErrInsufficientLiquidity
+	}
+	return quotient.Sub(sqrtPriceX96, quotient), nil
+}
+
+// mulDiv returns floor(a * b / denominator).
+func mulDiv(a, b, denominator *big.Int) *big.Int {
+	x := new(big.Int).Mul(a, b)
+	return x.Quo(x, denominator)
+}
+
+// mulDivRoundingUp returns ceil(a * b / denominator).
+func mulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
+	return divRoundingUp(new(big.Int).Mul(a, b), denominator)
+}
+
+// divRoundingUp returns ceil(x / y) for non-negative x and positive y.
+func divRoundingUp(x, y *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+	if r.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
@@ -0,0 +1,350 @@
+package uniswapv3
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// The expected values in this file and in swapmath_test.go are taken from
+// the SqrtPriceMath and SwapMath tests of the Uniswap V3 core repository.
+
+// encodePriceSqrt returns the sqrtPriceX96 of the price reserve1/reserve0,
+// rounded down.
+func encodePriceSqrt(reserve1, reserve0 int64) *big.Int {
+	x := new(big.Int).Lsh(big.NewInt(reserve1), 192)
+	x.Quo(x, big.NewInt(reserve0))
+	return x.Sqrt(x)
+}
+
+// bigInt parses a decimal number.
+func bigInt(s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("invalid number " + s)
+	}
+	return x
+}
+
+// pow2 returns 2^n.
+func pow2(n uint) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), n)
+}
+
+func TestGetNextSqrtPriceFromInput(t *testing.T) {
+	maxUint128 := new(big.Int).Sub(pow2(128), big.NewInt(1))
+	tests := []struct {
+		name         string
+		sqrtPriceX96 *big.Int
+		liquidity    *big.Int
+		amountIn     *big.Int
+		zeroForOne   bool
+		expected     *big.Int
+		err          error
+	}{
+		{
+			name:         "fails if price is zero",
+			sqrtPriceX96: big.NewInt(0),
+			liquidity:    big.NewInt(1),
+			amountIn:     big.NewInt(1e17),
+			zeroForOne:   false,
+			err:          ErrSqrtRatioOutOfRange,
+		},
+		{
+			name:         "fails if liquidity is zero",
+			sqrtPriceX96: big.NewInt(1),
+			liquidity:    big.NewInt(0),
+			amountIn:     big.NewInt(1e17),
+			zeroForOne:   true,
+			err:          ErrInvalidLiquidity,
+		},
+		{
+			name:         "fails if input amount overflows the price",
+			sqrtPriceX96: maxUint160,
+			liquidity:    big.NewInt(1024),
+			amountIn:     big.NewInt(1024),
+			zeroForOne:   false,
+			err:          ErrSqrtRatioOutOfRange,
+		},
+		{
+			name:         "any input amount cannot underflow the price",
+			sqrtPriceX96: big.NewInt(1),
+			liquidity:    big.NewInt(1),
+			amountIn:     pow2(255),
+			zeroForOne:   true,
+			expected:     big.NewInt(1),
+		},
+		{
+			name:         "returns input price if amount in is zero and zeroForOne",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1e17),
+			amountIn:     big.NewInt(0),
+			zeroForOne:   true,
+			expected:     encodePriceSqrt(1, 1),
+		},
+		{
+			name:         "returns input price if amount in is zero and oneForZero",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1e17),
+			amountIn:     big.NewInt(0),
+			zeroForOne:   false,
+			expected:     encodePriceSqrt(1, 1),
+		},
+		{
+			// The product of the amount and the price overflows, so the
+			// fallback formula is used.
+			name:         "returns the minimum price for max inputs",
+			sqrtPriceX96: maxUint160,
+			liquidity:    maxUint128,
+			amountIn: new(big.Int).Sub(
+				maxUint256,
+				new(big.Int).Quo(new(big.Int).Lsh(maxUint128, 96), maxUint160),
+			),
+			zeroForOne: true,
+			expected:   big.NewInt(1),
+		},
+		{
+			name:         "input amount of 0.1 token1",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1e18),
+			amountIn:     big.NewInt(1e17),
+			zeroForOne:   false,
+			expected:     bigInt("87150978765690771352898345369"),
+		},
+		{
+			name:         "input amount of 0.1 token0",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1e18),
+			amountIn:     big.NewInt(1e17),
+			zeroForOne:   true,
+			expected:     bigInt("72025602285694852357767227579"),
+		},
+		{
+			name:         "amountIn > type(uint96).max and zeroForOne",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    bigInt("10000000000000000000"),
+			amountIn:     pow2(100),
+			zeroForOne:   true,
+			expected:     big.NewInt(624999999995069620),
+		},
+		{
+			name:         "can return 1 with enough amountIn and zeroForOne",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1),
+			amountIn:     new(big.Int).Quo(maxUint256, big.NewInt(2)),
+			zeroForOne:   true,
+			expected:     big.NewInt(1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetNextSqrtPriceFromInput(tt.sqrtPriceX96, tt.liquidity, tt.amountIn, tt.zeroForOne)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cmp(tt.expected) != 0 {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetNextSqrtPriceFromOutput(t *testing.T) {
+	sqrtPrice := bigInt("20282409603651670423947251286016")
+	tests := []struct {
+		name         string
+		sqrtPriceX96 *big.Int
+		liquidity    *big.Int
+		amountOut    *big.Int
+		zeroForOne   bool
+		expected     *big.Int
+		err          error
+	}{
+		{
+			name:         "fails if price is zero",
+			sqrtPriceX96: big.NewInt(0),
+			liquidity:    big.NewInt(1),
+			amountOut:    big.NewInt(1e17),
+			zeroForOne:   false,
+			err:          ErrSqrtRatioOutOfRange,
+		},
+		{
+			name:         "fails if liquidity is zero",
+			sqrtPriceX96: big.NewInt(1),
+			liquidity:    big.NewInt(0),
+			amountOut:    big.NewInt(1e17),
+			zeroForOne:   true,
+			err:          ErrInvalidLiquidity,
+		},
+		{
+			name:         "fails if output amount is exactly the virtual reserves of token0",
+			sqrtPriceX96: sqrtPrice,
+			liquidity:    big.NewInt(1024),
+			amountOut:    big.NewInt(4),
+			zeroForOne:   false,
+			err:          ErrInsufficientLiquidity,
+		},
+		{
+			name:         "fails if output amount is greater than the virtual reserves of token0",
+			sqrtPriceX96: sqrtPrice,
+			liquidity:    big.NewInt(1024),
+			amountOut:    big.NewInt(5),
+			zeroForOne:   false,
+			err:          ErrInsufficientLiquidity,
+		},
+		{
+			name:         "fails if output amount is greater than the virtual reserves of token1",
+			sqrtPriceX96: sqrtPrice,
+			liquidity:    big.NewInt(1024),
+			amountOut:    big.NewInt(262145),
+			zeroForOne:   true,
+			err:          ErrInsufficientLiquidity,
+		},
+		{
+			name:         "fails if output amount is exactly the virtual reserves of token1",
+			sqrtPriceX96: sqrtPrice,
+			liquidity:    big.NewInt(1024),
+			amountOut:    big.NewInt(262144),
+			zeroForOne:   true,
+			err:          ErrInsufficientLiquidity,
+		},
+		{
+			name:         "succeeds if output amount is just less than the virtual reserves of token1",
+			sqrtPriceX96: sqrtPrice,
+			liquidity:    big.NewInt(1024),
+			amountOut:    big.NewInt(262143),
+			zeroForOne:   true,
+			expected:     bigInt("77371252455336267181195264"),
+		},
+		{
+			name:         "returns input price if amount out is zero",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1e17),
+			amountOut:    big.NewInt(0),
+			zeroForOne:   true,
+			expected:     encodePriceSqrt(1, 1),
+		},
+		{
+			name:         "output amount of 0.1 token1",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1e18),
+			amountOut:    big.NewInt(1e17),
+			zeroForOne:   false,
+			expected:     bigInt("88031291682515930659493278152"),
+		},
+		{
+			name:         "output amount of 0.1 token0",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1e18),
+			amountOut:    big.NewInt(1e17),
+			zeroForOne:   true,
+			expected:     bigInt("71305346262837903834189555302"),
+		},
+		{
+			name:         "fails if amount out is impossible in the zeroForOne direction",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1),
+			amountOut:    maxUint256,
+			zeroForOne:   true,
+			err:          ErrInsufficientLiquidity,
+		},
+		{
+			name:         "fails if amount out is impossible in the oneForZero direction",
+			sqrtPriceX96: encodePriceSqrt(1, 1),
+			liquidity:    big.NewInt(1),
+			amountOut:    maxUint256,
+			zeroForOne:   false,
+			err:          ErrInsufficientLiquidity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetNextSqrtPriceFromOutput(tt.sqrtPriceX96, tt.liquidity, tt.amountOut, tt.zeroForOne)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Cmp(tt.expected) != 0 {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetAmountDelta(t *testing.T) {
+	var (
+		one     = encodePriceSqrt(1, 1)
+		two     = encodePriceSqrt(2, 1)
+		onePt21 = encodePriceSqrt(121, 100)
+	)
+	tests := []struct {
+		name     string
+		fn       func(a, b, liquidity *big.Int, roundUp bool) *big.Int
+		a, b     *big.Int
+		liq      *big.Int
+		roundUp  bool
+		expected *big.Int
+	}{
+		{name: "amount0 is zero without liquidity", fn: GetAmount0Delta, a: one, b: two, liq: big.NewInt(0), roundUp: true, expected: big.NewInt(0)},
+		{name: "amount0 is zero for equal prices", fn: GetAmount0Delta, a: one, b: one, liq: big.NewInt(0), roundUp: true, expected: big.NewInt(0)},
+		{name: "amount0 for price 1 to 1.21 rounded up", fn: GetAmount0Delta, a: one, b: onePt21, liq: big.NewInt(1e18), roundUp: true, expected: big.NewInt(90909090909090910)},
+		{name: "amount0 for price 1 to 1.21 rounded down", fn: GetAmount0Delta, a: one, b: onePt21, liq: big.NewInt(1e18), roundUp: false, expected: big.NewInt(90909090909090909)},
+		{name: "amount0 with prices in any order", fn: GetAmount0Delta, a: onePt21, b: one, liq: big.NewInt(1e18), roundUp: true, expected: big.NewInt(90909090909090910)},
+		{name: "amount1 is zero without liquidity", fn: GetAmount1Delta, a: one, b: two, liq: big.NewInt(0), roundUp: true, expected: big.NewInt(0)},
+		{name: "amount1 is zero for equal prices", fn: GetAmount1Delta, a: one, b: one, liq: big.NewInt(0), roundUp: true, expected: big.NewInt(0)},
+		{name: "amount1 for price 1 to 1.21 rounded up", fn: GetAmount1Delta, a: one, b: onePt21, liq: big.NewInt(1e18), roundUp: true, expected: big.NewInt(100000000000000000)},
+		{name: "amount1 for price 1 to 1.21 rounded down", fn: GetAmount1Delta, a: one, b: onePt21, liq: big.NewInt(1e18), roundUp: false, expected: big.NewInt(99999999999999999)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(tt.a, tt.b, tt.liq, tt.roundUp); got.Cmp(tt.expected) != 0 {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestGetAmount0DeltaOverflow checks prices whose product with the
+// liquidity overflows 256 bits.
+func TestGetAmount0DeltaOverflow(t *testing.T) {
+	var (
+		a   = new(big.Int).Lsh(pow2(45), 96)
+		b   = new(big.Int).Lsh(pow2(48), 96)
+		liq = big.NewInt(1e18)
+	)
+	up := GetAmount0Delta(a, b, liq, true)
+	down := GetAmount0Delta(a, b, liq, false)
+	if new(big.Int).Sub(up, down).Cmp(big.NewInt(1)) != 0 {
+		t.Fatalf("expected the rounded up amount %s to be one more than %s", up, down)
+	}
+}
+
+// TestSwapComputation checks a swap in which sqrtP * sqrtQ overflows.
+func TestSwapComputation(t *testing.T) {
+	var (
+		sqrtP     = bigInt("1025574284609383690408304870162715216695788925244")
+		liquidity = bigInt("50015962439936049619261659728067971248")
+		amountIn  = big.NewInt(406)
+	)
+	sqrtQ, err := GetNextSqrtPriceFromInput(sqrtP, liquidity, amountIn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := bigInt("1025574284609383582644711336373707553698163132913"); sqrtQ.Cmp(expected) != 0 {
+		t.Fatalf("expected sqrtQ %s, got %s", expected, sqrtQ)
+	}
+	if amount0 := GetAmount0Delta(sqrtQ, sqrtP, liquidity, true); amount0.Cmp(amountIn) != 0 {
+		t.Fatalf("expected amount0 %s, got %s", amountIn, amount0)
+	}
+}
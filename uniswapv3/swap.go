@@ -0,0 +1,184 @@
+package uniswapv3
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// This file is a port of the swap loop of the UniswapV3Pool contract. Fee
+// growth, protocol fees and oracle updates do not affect the swapped
+// amounts, so they are not tracked.
+
+// ErrInvalidPriceLimit is returned when the sqrt price limit of a swap is
+// not on the side of the current price the swap moves towards.
+var ErrInvalidPriceLimit = errors.New("uniswapv3: invalid sqrt price limit")
+
+// MissingTickError is returned when a swap crosses an initialized tick
+// whose liquidity was not loaded.
+type MissingTickError struct {
+	Tick int
+}
+
+// Error implements the error interface.
+func (e *MissingTickError) Error() string {
+	return fmt.Sprintf("uniswapv3: liquidity of tick %d is not loaded", e.Tick)
+}
+
+// PoolState is the state of a pool needed to simulate swaps offline.
+type PoolState struct {
+	SqrtPriceX96 *big.Int
+	Tick         int
+	Liquidity    *big.Int
+	Fee          uint32
+	TickSpacing  int
+
+	// TickBitmap holds the loaded words of the pool tick bitmap.
+	TickBitmap TickBitmap
+
+	// LiquidityNet maps the initialized ticks to the amount of liquidity
+	// added when the tick is crossed from left to right.
+	LiquidityNet map[int]*big.Int
+}
+
+// SwapResult is the result of a simulated swap.
+type SwapResult struct {
+	// Amount0 and Amount1 are the pool balance changes. They are positive
+	// for tokens sent to the pool and negative for tokens received from it.
+	Amount0 *big.Int
+	Amount1 *big.Int
+
+	// AmountIn is the amount of tokens sent to the pool, including the fee.
+	AmountIn *big.Int
+
+	// AmountOut is the amount of tokens received from the pool.
+	AmountOut *big.Int
+
+	// FeeAmount is the fee paid in the input token.
+	FeeAmount *big.Int
+
+	// SqrtPriceX96, Tick and Liquidity are the pool state after the swap.
+	SqrtPriceX96 *big.Int
+	Tick         int
+	Liquidity    *big.Int
+
+	// TicksCrossed is the number of initialized ticks crossed by the swap.
+	TicksCrossed int
+}
+
+// Swap simulates a swap of token0 for token1 (zeroForOne) or the other way
+// around, the same way the pool contract does. A positive amountSpecified
+// is an exact input amount, a negative one is an exact output amount. The
+// swap stops when the price reaches sqrtPriceLimitX96.
+//
+// The state is not modified. If the swap needs a bitmap word or a tick that
+// was not loaded, a *MissingWordError or *MissingTickError is returned, so
+// the missing data can be loaded and the swap simulated again.
+func (p *PoolState) Swap(zeroForOne bool, amountSpecified, sqrtPriceLimitX96 *big.Int) (*SwapResult, error) {
+	if amountSpecified.Sign() == 0 {
+		return nil, errors.New("uniswapv3: amount specified must not be zero")
+	}
+	if zeroForOne {
+		if sqrtPriceLimitX96.Cmp(p.SqrtPriceX96) >= 0 || sqrtPriceLimitX96.Cmp(MinSqrtRatio) <= 0 {
+			return nil, ErrInvalidPriceLimit
+		}
+	} else {
+		if sqrtPriceLimitX96.Cmp(p.SqrtPriceX96) <= 0 || sqrtPriceLimitX96.Cmp(MaxSqrtRatio) >= 0 {
+			return nil, ErrInvalidPriceLimit
+		}
+	}
+
+	var (
+		exactInput   = amountSpecified.Sign() > 0
+		remaining    = new(big.Int).Set(amountSpecified)
+		calculated   = new(big.Int)
+		feeAmount    = new(big.Int)
+		sqrtPriceX96 = new(big.Int).Set(p.SqrtPriceX96)
+		tick         = p.Tick
+		liquidity    = new(big.Int).Set(p.Liquidity)
+		ticksCrossed int
+	)
+	for remaining.Sign() != 0 && sqrtPriceX96.Cmp(sqrtPriceLimitX96) != 0 {
+		sqrtPriceStartX96 := sqrtPriceX96
+
+		tickNext, initialized, err := p.TickBitmap.NextInitializedTickWithinOneWord(tick, p.TickSpacing, zeroForOne)
+		if err != nil {
+			return nil, err
+		}
+		if tickNext < MinTick {
+			tickNext = MinTick
+		} else if tickNext > MaxTick {
+			tickNext = MaxTick
+		}
+		sqrtPriceNextX96, err := GetSqrtRatioAtTick(tickNext)
+		if err != nil {
+			return nil, err
+		}
+
+		// Swap up to the next tick, or to the price limit if it comes first.
+		sqrtPriceTargetX96 := sqrtPriceNextX96
+		if (zeroForOne && sqrtPriceNextX96.Cmp(sqrtPriceLimitX96) < 0) || (!zeroForOne && sqrtPriceNextX96.Cmp(sqrtPriceLimitX96) > 0) {
+			sqrtPriceTargetX96 = sqrtPriceLimitX96
+		}
+		step, err := ComputeSwapStep(sqrtPriceX96, sqrtPriceTargetX96, liquidity, remaining, p.Fee)
+		if err != nil {
+			return nil, err
+		}
+		sqrtPriceX96 = step.SqrtPriceNextX96
+		feeAmount.Add(feeAmount, step.FeeAmount)
+		if exactInput {
+			remaining.Sub(remaining, step.AmountIn)
+			remaining.Sub(remaining, step.FeeAmount)
+			calculated.Sub(calculated, step.AmountOut)
+		} else {
+			remaining.Add(remaining, step.AmountOut)
+			calculated.Add(calculated, step.AmountIn)
+			calculated.Add(calculated, step.FeeAmount)
+		}
+
+		// Cross the tick if the step reached it.
+		if sqrtPriceX96.Cmp(sqrtPriceNextX96) == 0 {
+			if initialized {
+				liquidityNet, ok := p.LiquidityNet[tickNext]
+				if !ok {
+					return nil, &MissingTickError{Tick: tickNext}
+				}
+				if zeroForOne {
+					liquidity.Sub(liquidity, liquidityNet)
+				} else {
+					liquidity.Add(liquidity, liquidityNet)
+				}
+				ticksCrossed++
+			}
+			if zeroForOne {
+				tick = tickNext - 1
+			} else {
+				tick = tickNext
+			}
+		} else if sqrtPriceX96.Cmp(sqrtPriceStartX96) != 0 {
+			if tick, err = GetTickAtSqrtRatio(sqrtPriceX96); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	res := &SwapResult{
+		FeeAmount:    feeAmount,
+		SqrtPriceX96: sqrtPriceX96,
+		Tick:         tick,
+		Liquidity:    liquidity,
+		TicksCrossed: ticksCrossed,
+	}
+	specifiedUsed := new(big.Int).Sub(amountSpecified, remaining)
+	if zeroForOne == exactInput {
+		res.Amount0, res.Amount1 = specifiedUsed, calculated
+	} else {
+		res.Amount0, res.Amount1 = calculated, specifiedUsed
+	}
+	if zeroForOne {
+		res.AmountIn, res.AmountOut = res.Amount0, new(big.Int).Neg(res.Amount1)
+	} else {
+		res.AmountIn, res.AmountOut = res.Amount1, new(big.Int).Neg(res.Amount0)
+	}
+	return res, nil
+}
@@ -0,0 +1,222 @@
+package uniswapv3
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// testPoolState returns the state of a pool at price 1 with two positions:
+// 1e18 of liquidity between ticks -600 and 600, and 2e18 of liquidity
+// between ticks -120 and 120.
+func testPoolState() *PoolState {
+	return &PoolState{
+		SqrtPriceX96: new(big.Int).Set(Q96),
+		Tick:         0,
+		Liquidity:    bigInt("3000000000000000000"),
+		Fee:          3000,
+		TickSpacing:  60,
+		TickBitmap:   newTickBitmap(60, -1, 0, -600, -120, 120, 600),
+		LiquidityNet: map[int]*big.Int{
+			-600: bigInt("1000000000000000000"),
+			-120: bigInt("2000000000000000000"),
+			120:  bigInt("-2000000000000000000"),
+			600:  bigInt("-1000000000000000000"),
+		},
+	}
+}
+
+// minLimit and maxLimit are the widest sqrt price limits.
+var (
+	minLimit = new(big.Int).Add(MinSqrtRatio, big.NewInt(1))
+	maxLimit = new(big.Int).Sub(MaxSqrtRatio, big.NewInt(1))
+)
+
+func TestSwapWithinRange(t *testing.T) {
+	p := testPoolState()
+	amount := big.NewInt(1e15)
+	res, err := p.Swap(true, amount, minLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without crossing a tick, the swap is a single step.
+	step, err := ComputeSwapStep(p.SqrtPriceX96, minLimit, p.Liquidity, amount, p.Fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TicksCrossed != 0 {
+		t.Fatalf("expected no ticks crossed, got %d", res.TicksCrossed)
+	}
+	if res.SqrtPriceX96.Cmp(step.SqrtPriceNextX96) != 0 {
+		t.Fatalf("expected sqrt price %s, got %s", step.SqrtPriceNextX96, res.SqrtPriceX96)
+	}
+	if res.AmountIn.Cmp(amount) != 0 {
+		t.Fatalf("expected amount in %s, got %s", amount, res.AmountIn)
+	}
+	if res.AmountOut.Cmp(step.AmountOut) != 0 {
+		t.Fatalf("expected amount out %s, got %s", step.AmountOut, res.AmountOut)
+	}
+	if res.Amount0.Cmp(amount) != 0 || res.Amount1.Cmp(new(big.Int).Neg(step.AmountOut)) != 0 {
+		t.Fatalf("unexpected pool balance changes %s, %s", res.Amount0, res.Amount1)
+	}
+}
+
+func TestSwapCrossesTicks(t *testing.T) {
+	tests := []struct {
+		name       string
+		zeroForOne bool
+		amount     *big.Int
+	}{
+		{name: "zeroForOne exact input", zeroForOne: true, amount: bigInt("30000000000000000")},
+		{name: "zeroForOne exact output", zeroForOne: true, amount: bigInt("-30000000000000000")},
+		{name: "oneForZero exact input", zeroForOne: false, amount: bigInt("30000000000000000")},
+		{name: "oneForZero exact output", zeroForOne: false, amount: bigInt("-30000000000000000")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPoolState()
+			limit := maxLimit
+			if tt.zeroForOne {
+				limit = minLimit
+			}
+			res, err := p.Swap(tt.zeroForOne, tt.amount, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The swap leaves the range of the smaller position, but not the
+			// range of the larger one.
+			if res.TicksCrossed != 1 {
+				t.Fatalf("expected 1 tick crossed, got %d", res.TicksCrossed)
+			}
+			if expected := big.NewInt(1e18); res.Liquidity.Cmp(expected) != 0 {
+				t.Fatalf("expected liquidity %s, got %s", expected, res.Liquidity)
+			}
+			if tt.zeroForOne && (res.Tick >= -120 || res.Tick < -600) {
+				t.Fatalf("expected tick between -600 and -120, got %d", res.Tick)
+			}
+			if !tt.zeroForOne && (res.Tick < 120 || res.Tick >= 600) {
+				t.Fatalf("expected tick between 120 and 600, got %d", res.Tick)
+			}
+			if tick, _ := GetTickAtSqrtRatio(res.SqrtPriceX96); tick != res.Tick {
+				t.Fatalf("tick %d does not match the sqrt price, expected %d", res.Tick, tick)
+			}
+
+			// The specified amount is swapped in full.
+			if tt.amount.Sign() > 0 && res.AmountIn.Cmp(tt.amount) != 0 {
+				t.Fatalf("expected amount in %s, got %s", tt.amount, res.AmountIn)
+			}
+			if tt.amount.Sign() < 0 && res.AmountOut.Cmp(new(big.Int).Neg(tt.amount)) != 0 {
+				t.Fatalf("expected amount out %s, got %s", new(big.Int).Neg(tt.amount), res.AmountOut)
+			}
+
+			// The state is not modified.
+			if p.SqrtPriceX96.Cmp(Q96) != 0 || p.Tick != 0 || p.Liquidity.Cmp(bigInt("3000000000000000000")) != 0 {
+				t.Fatal("the pool state was modified")
+			}
+		})
+	}
+}
+
+// TestSwapCrossesTickInSteps checks that a swap crossing a tick equals the
+// sum of the swap steps on both sides of the tick.
+func TestSwapCrossesTickInSteps(t *testing.T) {
+	p := testPoolState()
+	amount := bigInt("30000000000000000")
+	res, err := p.Swap(true, amount, minLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqrtPriceTick, _ := GetSqrtRatioAtTick(-120)
+	step1, err := ComputeSwapStep(p.SqrtPriceX96, sqrtPriceTick, p.Liquidity, amount, p.Fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := new(big.Int).Sub(amount, step1.AmountIn)
+	remaining.Sub(remaining, step1.FeeAmount)
+	sqrtPriceNextTick, _ := GetSqrtRatioAtTick(-600)
+	step2, err := ComputeSwapStep(sqrtPriceTick, sqrtPriceNextTick, big.NewInt(1e18), remaining, p.Fee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amountOut := new(big.Int).Add(step1.AmountOut, step2.AmountOut)
+	if res.AmountOut.Cmp(amountOut) != 0 {
+		t.Fatalf("expected amount out %s, got %s", amountOut, res.AmountOut)
+	}
+	if res.SqrtPriceX96.Cmp(step2.SqrtPriceNextX96) != 0 {
+		t.Fatalf("expected sqrt price %s, got %s", step2.SqrtPriceNextX96, res.SqrtPriceX96)
+	}
+	feeAmount := new(big.Int).Add(step1.FeeAmount, step2.FeeAmount)
+	if res.FeeAmount.Cmp(feeAmount) != 0 {
+		t.Fatalf("expected fee %s, got %s", feeAmount, res.FeeAmount)
+	}
+}
+
+func TestSwapStopsAtPriceLimit(t *testing.T) {
+	p := testPoolState()
+	limit, _ := GetSqrtRatioAtTick(-300)
+	amount := bigInt("1000000000000000000")
+	res, err := p.Swap(true, amount, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SqrtPriceX96.Cmp(limit) != 0 {
+		t.Fatalf("expected the swap to stop at %s, got %s", limit, res.SqrtPriceX96)
+	}
+	if res.AmountIn.Cmp(amount) >= 0 {
+		t.Fatalf("expected only a part of %s to be swapped, got %s", amount, res.AmountIn)
+	}
+	if res.TicksCrossed != 1 {
+		t.Fatalf("expected 1 tick crossed, got %d", res.TicksCrossed)
+	}
+}
+
+func TestSwapMissingData(t *testing.T) {
+	t.Run("missing word", func(t *testing.T) {
+		p := testPoolState()
+		delete(p.TickBitmap, -1)
+		_, err := p.Swap(true, bigInt("30000000000000000"), minLimit)
+		var missing *MissingWordError
+		if !errors.As(err, &missing) || missing.WordPos != -1 {
+			t.Fatalf("expected missing word -1, got %v", err)
+		}
+	})
+	t.Run("missing word past the loaded ones", func(t *testing.T) {
+		p := testPoolState()
+		_, err := p.Swap(false, bigInt("1000000000000000000000"), maxLimit)
+		var missing *MissingWordError
+		if !errors.As(err, &missing) || missing.WordPos != 1 {
+			t.Fatalf("expected missing word 1, got %v", err)
+		}
+	})
+	t.Run("missing tick", func(t *testing.T) {
+		p := testPoolState()
+		delete(p.LiquidityNet, -120)
+		_, err := p.Swap(true, bigInt("30000000000000000"), minLimit)
+		var missing *MissingTickError
+		if !errors.As(err, &missing) || missing.Tick != -120 {
+			t.Fatalf("expected missing tick -120, got %v", err)
+		}
+	})
+}
+
+func TestSwapInvalidPriceLimit(t *testing.T) {
+	p := testPoolState()
+	tests := []struct {
+		name       string
+		zeroForOne bool
+		limit      *big.Int
+	}{
+		{name: "zeroForOne above the price", zeroForOne: true, limit: new(big.Int).Add(Q96, big.NewInt(1))},
+		{name: "zeroForOne at MinSqrtRatio", zeroForOne: true, limit: MinSqrtRatio},
+		{name: "oneForZero below the price", zeroForOne: false, limit: new(big.Int).Sub(Q96, big.NewInt(1))},
+		{name: "oneForZero at MaxSqrtRatio", zeroForOne: false, limit: MaxSqrtRatio},
+	}
+	for _, tt := range tests {
+		if _, err := p.Swap(tt.zeroForOne, big.NewInt(1e15), tt.limit); !errors.Is(err, ErrInvalidPriceLimit) {
+			t.Errorf("%s: expected ErrInvalidPriceLimit, got %v", tt.name, err)
+		}
+	}
+}
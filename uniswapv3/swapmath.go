@@ -0,0 +1,116 @@
+package uniswapv3
+
+import (
+	"math/big"
+)
+
+// This file is a port of the Uniswap V3 SwapMath library. The results match
+// the Solidity implementation bit for bit.
+
+// feeDenominator is the denominator of pool fees, which are expressed in
+// hundredths of a bip.
+var feeDenominator = big.NewInt(1e6)
+
+// SwapStep is the result of a single swap step within a liquidity range.
+type SwapStep struct {
+	// SqrtPriceNextX96 is the sqrt price after the step. It equals the
+	// target price if the whole range was used.
+	SqrtPriceNextX96 *big.Int
+
+	// AmountIn is the amount of tokens sent to the pool, excluding the fee.
+	AmountIn *big.Int
+
+	// AmountOut is the amount of tokens received from the pool.
+	AmountOut *big.Int
+
+	// FeeAmount is the fee paid in the input token.
+	FeeAmount *big.Int
+}
+
+// ComputeSwapStep computes the result of swapping amountRemaining within a
+// range of constant liquidity, from the current sqrt price towards the
+// target one. A positive amountRemaining is an exact input amount, a
+// negative one is an exact output amount. The fee is expressed in
+// hundredths of a bip.
+func ComputeSwapStep(sqrtRatioCurrentX96, sqrtRatioTargetX96, liquidity, amountRemaining *big.Int, fee uint32) (*SwapStep, error) {
+	var (
+		zeroForOne    = sqrtRatioCurrentX96.Cmp(sqrtRatioTargetX96) >= 0
+		exactIn       = amountRemaining.Sign() >= 0
+		feePips       = big.NewInt(int64(fee))
+		feeComplement = new(big.Int).Sub(feeDenominator, feePips)
+		absRemaining  = new(big.Int).Abs(amountRemaining)
+		sqrtRatioNext *big.Int
+		amountIn      *big.Int
+		amountOut     *big.Int
+		err           error
+	)
+	if exactIn {
+		amountRemainingLessFee := mulDiv(absRemaining, feeComplement, feeDenominator)
+		if zeroForOne {
+			amountIn = GetAmount0Delta(sqrtRatioTargetX96, sqrtRatioCurrentX96, liquidity, true)
+		} else {
+			amountIn = GetAmount1Delta(sqrtRatioCurrentX96, sqrtRatioTargetX96, liquidity, true)
+		}
+		if amountRemainingLessFee.Cmp(amountIn) >= 0 {
+			sqrtRatioNext = sqrtRatioTargetX96
+		} else {
+			sqrtRatioNext, err = GetNextSqrtPriceFromInput(sqrtRatioCurrentX96, liquidity, amountRemainingLessFee, zeroForOne)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		if zeroForOne {
+			amountOut = GetAmount1Delta(sqrtRatioTargetX96, sqrtRatioCurrentX96, liquidity, false)
+		} else {
+			amountOut = GetAmount0Delta(sqrtRatioCurrentX96, sqrtRatioTargetX96, liquidity, false)
+		}
+		if absRemaining.Cmp(amountOut) >= 0 {
+			sqrtRatioNext = sqrtRatioTargetX96
+		} else {
+			sqrtRatioNext, err = GetNextSqrtPriceFromOutput(sqrtRatioCurrentX96, liquidity, absRemaining, zeroForOne)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Recompute the amounts if the target price was not reached.
+	max := sqrtRatioTargetX96.Cmp(sqrtRatioNext) == 0
+	if zeroForOne {
+		if !max || !exactIn {
+			amountIn = GetAmount0Delta(sqrtRatioNext, sqrtRatioCurrentX96, liquidity, true)
+		}
+		if !max || exactIn {
+			amountOut = GetAmount1Delta(sqrtRatioNext, sqrtRatioCurrentX96, liquidity, false)
+		}
+	} else {
+		if !max || !exactIn {
+			amountIn = GetAmount1Delta(sqrtRatioCurrentX96, sqrtRatioNext, liquidity, true)
+		}
+		if !max || exactIn {
+			amountOut = GetAmount0Delta(sqrtRatioCurrentX96, sqrtRatioNext, liquidity, false)
+		}
+	}
+
+	// The output may not exceed the requested amount.
+	if !exactIn && amountOut.Cmp(absRemaining) > 0 {
+		amountOut = absRemaining
+	}
+
+	// If the target price was not reached, the whole remaining input is
+	// used, so the rest of it is the fee.
+	var feeAmount *big.Int
+	if exactIn && sqrtRatioNext.Cmp(sqrtRatioTargetX96) != 0 {
+		feeAmount = new(big.Int).Sub(absRemaining, amountIn)
+	} else {
+		feeAmount = mulDivRoundingUp(amountIn, feePips, feeComplement)
+	}
+
+	return &SwapStep{
+		SqrtPriceNextX96: new(big.Int).Set(sqrtRatioNext),
+		AmountIn:         amountIn,
+		AmountOut:        amountOut,
+		FeeAmount:        feeAmount,
+	}, nil
+}
@@ -0,0 +1,166 @@
+package uniswapv3
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestComputeSwapStep(t *testing.T) {
+	sqrtP := bigInt("20282409603651670423947251286016")
+	tests := []struct {
+		name            string
+		current, target *big.Int
+		liquidity       *big.Int
+		amount          *big.Int
+		fee             uint32
+		expected        SwapStep
+	}{
+		{
+			name:      "exact amount in that gets capped at the price target in oneForZero",
+			current:   encodePriceSqrt(1, 1),
+			target:    encodePriceSqrt(101, 100),
+			liquidity: bigInt("2000000000000000000"),
+			amount:    big.NewInt(1e18),
+			fee:       600,
+			expected: SwapStep{
+				SqrtPriceNextX96: encodePriceSqrt(101, 100),
+				AmountIn:         big.NewInt(9975124224178055),
+				AmountOut:        big.NewInt(9925619580021728),
+				FeeAmount:        big.NewInt(5988667735148),
+			},
+		},
+		{
+			name:      "exact amount out that gets capped at the price target in oneForZero",
+			current:   encodePriceSqrt(1, 1),
+			target:    encodePriceSqrt(101, 100),
+			liquidity: bigInt("2000000000000000000"),
+			amount:    big.NewInt(-1e18),
+			fee:       600,
+			expected: SwapStep{
+				SqrtPriceNextX96: encodePriceSqrt(101, 100),
+				AmountIn:         big.NewInt(9975124224178055),
+				AmountOut:        big.NewInt(9925619580021728),
+				FeeAmount:        big.NewInt(5988667735148),
+			},
+		},
+		{
+			name:      "exact amount in that is fully spent in oneForZero",
+			current:   encodePriceSqrt(1, 1),
+			target:    encodePriceSqrt(1000, 100),
+			liquidity: bigInt("2000000000000000000"),
+			amount:    big.NewInt(1e18),
+			fee:       600,
+			expected: SwapStep{
+				// The price moves by amountIn / liquidity, without the fee.
+				SqrtPriceNextX96: bigInt("118818475322642227089037862318"),
+				AmountIn:         big.NewInt(999400000000000000),
+				AmountOut:        big.NewInt(666399946655997866),
+				FeeAmount:        big.NewInt(600000000000000),
+			},
+		},
+		{
+			name:      "exact amount out that is fully received in oneForZero",
+			current:   encodePriceSqrt(1, 1),
+			target:    encodePriceSqrt(10000, 100),
+			liquidity: bigInt("2000000000000000000"),
+			amount:    big.NewInt(-1e18),
+			fee:       600,
+			expected: SwapStep{
+				SqrtPriceNextX96: new(big.Int).Lsh(Q96, 1),
+				AmountIn:         bigInt("2000000000000000000"),
+				AmountOut:        big.NewInt(1e18),
+				FeeAmount:        big.NewInt(1200720432259356),
+			},
+		},
+		{
+			name:      "amount out is capped at the desired amount out",
+			current:   bigInt("417332158212080721273783715441582"),
+			target:    bigInt("1452870262520218020823638996"),
+			liquidity: bigInt("159344665391607089467575320103"),
+			amount:    big.NewInt(-1),
+			fee:       1,
+			expected: SwapStep{
+				SqrtPriceNextX96: bigInt("417332158212080721273783715441581"),
+				AmountIn:         big.NewInt(1),
+				AmountOut:        big.NewInt(1),
+				FeeAmount:        big.NewInt(1),
+			},
+		},
+		{
+			name:      "target price of 1 uses partial input amount",
+			current:   big.NewInt(2),
+			target:    big.NewInt(1),
+			liquidity: big.NewInt(1),
+			amount:    bigInt("3915081100057732413702495386755767"),
+			fee:       1,
+			expected: SwapStep{
+				SqrtPriceNextX96: big.NewInt(1),
+				AmountIn:         bigInt("39614081257132168796771975168"),
+				AmountOut:        big.NewInt(0),
+				FeeAmount:        bigInt("39614120871253040049813"),
+			},
+		},
+		{
+			name:      "entire input amount taken as fee",
+			current:   big.NewInt(2413),
+			target:    bigInt("79887613182836312"),
+			liquidity: bigInt("1985041575832132834610021537970"),
+			amount:    big.NewInt(10),
+			fee:       1872,
+			expected: SwapStep{
+				SqrtPriceNextX96: big.NewInt(2413),
+				AmountIn:         big.NewInt(0),
+				AmountOut:        big.NewInt(0),
+				FeeAmount:        big.NewInt(10),
+			},
+		},
+		{
+			name:      "intermediate insufficient liquidity in zeroForOne exact output",
+			current:   sqrtP,
+			target:    new(big.Int).Quo(new(big.Int).Mul(sqrtP, big.NewInt(11)), big.NewInt(10)),
+			liquidity: big.NewInt(1024),
+			amount:    big.NewInt(-4),
+			fee:       3000,
+			expected: SwapStep{
+				SqrtPriceNextX96: new(big.Int).Quo(new(big.Int).Mul(sqrtP, big.NewInt(11)), big.NewInt(10)),
+				AmountIn:         big.NewInt(26215),
+				AmountOut:        big.NewInt(0),
+				FeeAmount:        big.NewInt(79),
+			},
+		},
+		{
+			name:      "intermediate insufficient liquidity in oneForZero exact output",
+			current:   sqrtP,
+			target:    new(big.Int).Quo(new(big.Int).Mul(sqrtP, big.NewInt(9)), big.NewInt(10)),
+			liquidity: big.NewInt(1024),
+			amount:    big.NewInt(-263000),
+			fee:       3000,
+			expected: SwapStep{
+				SqrtPriceNextX96: new(big.Int).Quo(new(big.Int).Mul(sqrtP, big.NewInt(9)), big.NewInt(10)),
+				AmountIn:         big.NewInt(1),
+				AmountOut:        big.NewInt(26214),
+				FeeAmount:        big.NewInt(1),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, err := ComputeSwapStep(tt.current, tt.target, tt.liquidity, tt.amount, tt.fee)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if step.SqrtPriceNextX96.Cmp(tt.expected.SqrtPriceNextX96) != 0 {
+				t.Errorf("expected sqrt price %s, got %s", tt.expected.SqrtPriceNextX96, step.SqrtPriceNextX96)
+			}
+			if step.AmountIn.Cmp(tt.expected.AmountIn) != 0 {
+				t.Errorf("expected amount in %s, got %s", tt.expected.AmountIn, step.AmountIn)
+			}
+			if step.AmountOut.Cmp(tt.expected.AmountOut) != 0 {
+				t.Errorf("expected amount out %s, got %s", tt.expected.AmountOut, step.AmountOut)
+			}
+			if step.FeeAmount.Cmp(tt.expected.FeeAmount) != 0 {
+				t.Errorf("expected fee %s, got %s", tt.expected.FeeAmount, step.FeeAmount)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package uniswapv3
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// This file is a port of the Uniswap V3 TickBitmap library.
+
+// MissingWordError is returned when a swap needs a word of the tick bitmap
+// that was not loaded.
+type MissingWordError struct {
+	WordPos int16
+}
+
+// Error implements the error interface.
+func (e *MissingWordError) Error() string {
+	return fmt.Sprintf("uniswapv3: tick bitmap word %d is not loaded", e.WordPos)
+}
+
+// TickBitmap holds words of the tick bitmap of a pool, keyed by the word
+// position. Each word stores the initialized state of 256 ticks that are
+// multiples of the tick spacing.
+type TickBitmap map[int16]*big.Int
+
+// WordPosition returns the position of the bitmap word that holds the given
+// tick.
+func WordPosition(tick, tickSpacing int) int16 {
+	return int16(floorDiv(tick, tickSpacing) >> 8)
+}
+
+// NextInitializedTickWithinOneWord returns the next initialized tick in the
+// same bitmap word as the given tick. If lte is true, the search is made at
+// or to the left of the tick, otherwise it is made strictly to the right of
+// it. If no initialized tick is found, the last tick of the word in the
+// search direction is returned and initialized is false.
+func (b TickBitmap) NextInitializedTickWithinOneWord(tick, tickSpacing int, lte bool) (next int, initialized bool, err error) {
+	compressed := floorDiv(tick, tickSpacing)
+	if !lte {
+		compressed++
+	}
+	wordPos, bitPos := int16(compressed>>8), compressed&0xff
+	word, ok := b[wordPos]
+	if !ok {
+		return 0, false, &MissingWordError{WordPos: wordPos}
+	}
+	if lte {
+		// All the bits at or to the right of the current bit.
+		mask := new(big.Int).Lsh(big.NewInt(1), uint(bitPos+1))
+		mask.Sub(mask, big.NewInt(1))
+		masked := mask.And(mask, word)
+		if masked.Sign() == 0 {
+			return (compressed - bitPos) * tickSpacing, false, nil
+		}
+		return (compressed - (bitPos - (masked.BitLen() - 1))) * tickSpacing, true, nil
+	}
+	// All the bits at or to the left of the current bit.
+	masked := new(big.Int).Rsh(word, uint(bitPos))
+	if masked.Sign() == 0 {
+		return (compressed + (255 - bitPos)) * tickSpacing, false, nil
+	}
+	return (compressed + int(masked.TrailingZeroBits())) * tickSpacing, true, nil
+}
+
+// InitializedTicks returns the initialized ticks stored in the bitmap word
+// at the given position, in ascending order.
+func InitializedTicks(wordPos int16, word *big.Int, tickSpacing int) []int {
+	var ticks []int
+	for i := 0; i < word.BitLen(); i++ {
+		if word.Bit(i) == 1 {
+			ticks = append(ticks, (int(wordPos)<<8+i)*tickSpacing)
+		}
+	}
+	return ticks
+}
@@ -0,0 +1,150 @@
+package uniswapv3
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// newTickBitmap returns a bitmap with the given ticks initialized and the
+// words between fromWord and toWord loaded.
+func newTickBitmap(tickSpacing int, fromWord, toWord int16, ticks ...int) TickBitmap {
+	b := make(TickBitmap)
+	for pos := fromWord; pos <= toWord; pos++ {
+		b[pos] = new(big.Int)
+	}
+	for _, tick := range ticks {
+		compressed := floorDiv(tick, tickSpacing)
+		word := b[int16(compressed>>8)]
+		word.SetBit(word, compressed&0xff, 1)
+	}
+	return b
+}
+
+// The test cases are taken from the TickBitmap tests of the Uniswap V3 core
+// repository.
+func TestNextInitializedTickWithinOneWord(t *testing.T) {
+	b := newTickBitmap(1, -4, 4, -200, -55, -4, 70, 78, 84, 139, 240, 535)
+	tests := []struct {
+		tick        int
+		lte         bool
+		next        int
+		initialized bool
+	}{
+		// Search to the right.
+		{tick: 78, lte: false, next: 84, initialized: true},
+		{tick: -55, lte: false, next: -4, initialized: true},
+		{tick: 77, lte: false, next: 78, initialized: true},
+		{tick: -56, lte: false, next: -55, initialized: true},
+		{tick: 255, lte: false, next: 511, initialized: false},
+		{tick: 383, lte: false, next: 511, initialized: false},
+		{tick: -257, lte: false, next: -200, initialized: true},
+		{tick: -1, lte: false, next: 70, initialized: true},
+
+		// Search to the left, including the tick itself.
+		{tick: 78, lte: true, next: 78, initialized: true},
+		{tick: 79, lte: true, next: 78, initialized: true},
+		{tick: 258, lte: true, next: 256, initialized: false},
+		{tick: 256, lte: true, next: 256, initialized: false},
+		{tick: 72, lte: true, next: 70, initialized: true},
+		{tick: -257, lte: true, next: -512, initialized: false},
+		{tick: 1023, lte: true, next: 768, initialized: false},
+		{tick: 900, lte: true, next: 768, initialized: false},
+		{tick: -1, lte: true, next: -4, initialized: true},
+		{tick: -256, lte: true, next: -256, initialized: false},
+	}
+	for _, tt := range tests {
+		next, initialized, err := b.NextInitializedTickWithinOneWord(tt.tick, 1, tt.lte)
+		if err != nil {
+			t.Fatalf("tick %d, lte %t: %v", tt.tick, tt.lte, err)
+		}
+		if next != tt.next || initialized != tt.initialized {
+			t.Errorf("tick %d, lte %t: got %d, %t, expected %d, %t", tt.tick, tt.lte, next, initialized, tt.next, tt.initialized)
+		}
+	}
+}
+
+func TestNextInitializedTickWithinOneWordTickSpacing(t *testing.T) {
+	b := newTickBitmap(60, -1, 0, -120, -60*256, 60*255)
+	tests := []struct {
+		tick        int
+		lte         bool
+		next        int
+		initialized bool
+	}{
+		// Ticks between multiples of the spacing are rounded down.
+		{tick: -61, lte: true, next: -120, initialized: true},
+		{tick: -1, lte: true, next: -120, initialized: true},
+		{tick: -121, lte: true, next: -60 * 256, initialized: true},
+		{tick: -121, lte: false, next: -120, initialized: true},
+		{tick: -120, lte: false, next: -60, initialized: false},
+
+		// Edges of the words.
+		{tick: -60 * 256, lte: true, next: -60 * 256, initialized: true},
+		{tick: 0, lte: true, next: 0, initialized: false},
+		{tick: 0, lte: false, next: 60 * 255, initialized: true},
+		{tick: 60 * 255, lte: true, next: 60 * 255, initialized: true},
+	}
+	for _, tt := range tests {
+		next, initialized, err := b.NextInitializedTickWithinOneWord(tt.tick, 60, tt.lte)
+		if err != nil {
+			t.Fatalf("tick %d, lte %t: %v", tt.tick, tt.lte, err)
+		}
+		if next != tt.next || initialized != tt.initialized {
+			t.Errorf("tick %d, lte %t: got %d, %t, expected %d, %t", tt.tick, tt.lte, next, initialized, tt.next, tt.initialized)
+		}
+	}
+}
+
+func TestNextInitializedTickWithinOneWordMissingWord(t *testing.T) {
+	b := newTickBitmap(60, -1, 0)
+	tests := []struct {
+		tick    int
+		lte     bool
+		wordPos int16
+	}{
+		{tick: -60*256 - 1, lte: true, wordPos: -2},
+		{tick: 60 * 255, lte: false, wordPos: 1},
+	}
+	for _, tt := range tests {
+		_, _, err := b.NextInitializedTickWithinOneWord(tt.tick, 60, tt.lte)
+		var missing *MissingWordError
+		if !errors.As(err, &missing) || missing.WordPos != tt.wordPos {
+			t.Errorf("tick %d, lte %t: expected missing word %d, got %v", tt.tick, tt.lte, tt.wordPos, err)
+		}
+	}
+}
+
+func TestWordPosition(t *testing.T) {
+	tests := []struct {
+		tick, tickSpacing int
+		wordPos           int16
+	}{
+		{tick: 0, tickSpacing: 1, wordPos: 0},
+		{tick: 255, tickSpacing: 1, wordPos: 0},
+		{tick: 256, tickSpacing: 1, wordPos: 1},
+		{tick: -1, tickSpacing: 1, wordPos: -1},
+		{tick: -256, tickSpacing: 1, wordPos: -1},
+		{tick: -257, tickSpacing: 1, wordPos: -2},
+		{tick: -1, tickSpacing: 60, wordPos: -1},
+		{tick: 60*256 - 1, tickSpacing: 60, wordPos: 0},
+		{tick: MinTick, tickSpacing: 1, wordPos: -3466},
+		{tick: MaxTick, tickSpacing: 1, wordPos: 3465},
+	}
+	for _, tt := range tests {
+		if got := WordPosition(tt.tick, tt.tickSpacing); got != tt.wordPos {
+			t.Errorf("WordPosition(%d, %d) = %d, expected %d", tt.tick, tt.tickSpacing, got, tt.wordPos)
+		}
+	}
+}
+
+func TestInitializedTicks(t *testing.T) {
+	b := newTickBitmap(60, -1, 0, -60*256, -120, 0, 60*255)
+	if got, expected := InitializedTicks(-1, b[-1], 60), []int{-60 * 256, -120}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("word -1: got %v, expected %v", got, expected)
+	}
+	if got, expected := InitializedTicks(0, b[0], 60), []int{0, 60 * 255}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("word 0: got %v, expected %v", got, expected)
+	}
+}
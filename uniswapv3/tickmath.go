@@ -0,0 +1,210 @@
+package uniswapv3
+
+import (
+	"errors"
+	"math/big"
+)
+
+// This file is a port of the Uniswap V3 TickMath library. The results match
+// the Solidity implementation bit for bit.
+
+// Tick range supported by Uniswap V3 pools.
+const (
+	MinTick = -887272
+	MaxTick = -MinTick
+)
+
+// Sqrt price range supported by Uniswap V3 pools. MinSqrtRatio is the sqrt
+// price at MinTick and MaxSqrtRatio is the sqrt price at MaxTick.
+var (
+	MinSqrtRatio    = big.NewInt(4295128739)
+	MaxSqrtRatio, _ = new(big.Int).SetString("1461446703485210103287273052203988822378723970342", 10)
+)
+
+var (
+	// ErrTickOutOfRange is returned when a tick is outside the
+	// [MinTick, MaxTick] range.
+	ErrTickOutOfRange = errors.New("uniswapv3: tick out of range")
+
+	// ErrSqrtRatioOutOfRange is returned when a sqrt price is outside the
+	// [MinSqrtRatio, MaxSqrtRatio) range.
+	ErrSqrtRatioOutOfRange = errors.New("uniswapv3: sqrt ratio out of range")
+)
+
+// sqrtRatioFactors are the 128.128 fixed point values of 1/sqrt(1.0001)^(2^i).
+var sqrtRatioFactors = []*big.Int{
+	mustHex("fffcb933bd6fad37aa2d162d1a594001"),
+	mustHex("fff97272373d413259a46990580e213a"),
+	mustHex("fff2e50f5f656932ef12357cf3c7fdcc"),
+	mustHex("ffe5caca7e10e4e61c3624eaa0941cd0"),
+	mustHex("ffcb9843d60f6159c9db58835c926644"),
+	mustHex("ff973b41fa98c081472e6896dfb254c0"),
+	mustHex("ff2ea16466c96a3843ec78b326b52861"),
+	mustHex("fe5dee046a99a2a811c461f1969c3053"),
+	mustHex("fcbe86c7900a88aedcffc83b479aa3a4"),
+	mustHex("f987a7253ac413176f2b074cf7815e54"),
+	mustHex("f3392b0822b70005940c7a398e4b70f3"),
+	mustHex("e7159475a2c29b7443b29c7fa6e889d9"),
+	mustHex("d097f3bdfd2022b8845ad8f792aa5825"),
+	mustHex("a9f746462d870fdf8a65dc1f90e061e5"),
+	mustHex("70d869a156d2a1b890bb3df62baf32f7"),
+	mustHex("31be135f97d08fd981231505542fcfa6"),
+	mustHex("9aa508b5b7a84e1c677de54f3e99bc9"),
+	mustHex("5d6af8dedb81196699c329225ee604"),
+	mustHex("2216e584f5fa1ea926041bedfe98"),
+	mustHex("48a170391f7dc42444e8fa2"),
+}
+
+var (
+	q128       = new(big.Int).Lsh(big.NewInt(1), 128)
+	maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	log2ToLogSqrt10001 = mustDec("255738958999603826347141")
+	tickLowOffset      = mustDec("3402992956809132418596140100660247210")
+	tickHighOffset     = mustDec("291339464771989622907027621153398088495")
+)
+
+// GetSqrtRatioAtTick returns sqrt(1.0001^tick) * 2^96.
+func GetSqrtRatioAtTick(tick int) (*big.Int, error) {
+	absTick := tick
+	if absTick < 0 {
+		absTick = -absTick
+	}
+	if absTick > MaxTick {
+		return nil, ErrTickOutOfRange
+	}
+	ratio := new(big.Int).Set(q128)
+	for i, factor := range sqrtRatioFactors {
+		if absTick&(1<<i) != 0 {
+			ratio.Mul(ratio, factor)
+			ratio.Rsh(ratio, 128)
+		}
+	}
+	if tick > 0 {
+		ratio.Quo(maxUint256, ratio)
+	}
+	// Round up to go from a Q128.128 to a Q128.96 number, so that
+	// GetTickAtSqrtRatio of the result is always consistent.
+	rounded := new(big.Int).Rsh(ratio, 32)
+	if new(big.Int).And(ratio, big.NewInt(0xffffffff)).Sign() != 0 {
+		rounded.Add(rounded, big.NewInt(1))
+	}
+	return rounded, nil
+}
+
+// GetTickAtSqrtRatio returns the greatest tick for which
+// GetSqrtRatioAtTick(tick) <= sqrtPriceX96.
+func GetTickAtSqrtRatio(sqrtPriceX96 *big.Int) (int, error) {
+	if sqrtPriceX96.Cmp(MinSqrtRatio) < 0 || sqrtPriceX96.Cmp(MaxSqrtRatio) >= 0 {
+		return 0, ErrSqrtRatioOutOfRange
+	}
+	ratio := new(big.Int).Lsh(sqrtPriceX96, 32)
+
+	// Normalize the ratio to a 1.127 fixed point number in [1, 2).
+	msb := ratio.BitLen() - 1
+	r := new(big.Int)
+	if msb >= 128 {
+		r.Rsh(ratio, uint(msb-127))
+	} else {
+		r.Lsh(ratio, uint(127-msb))
+	}
+
+	// Compute 14 fractional bits of log2 by repeated squaring.
+	log2 := new(big.Int).Lsh(big.NewInt(int64(msb-128)), 64)
+	for bit := 63; bit >= 50; bit-- {
+		r.Mul(r, r)
+		r.Rsh(r, 127)
+		f := r.Bit(128)
+		if f == 1 {
+			log2.SetBit(log2, bit, 1)
+			r.Rsh(r, 1)
+		}
+	}
+
+	logSqrt10001 := new(big.Int).Mul(log2, log2ToLogSqrt10001)
+	tickLow := int(new(big.Int).Rsh(new(big.Int).Sub(logSqrt10001, tickLowOffset), 128).Int64())
+	tickHigh := int(new(big.Int).Rsh(new(big.Int).Add(logSqrt10001, tickHighOffset), 128).Int64())
+	if tickLow == tickHigh {
+		return tickLow, nil
+	}
+	sqrtRatioHigh, err := GetSqrtRatioAtTick(tickHigh)
+	if err != nil {
+		return 0, err
+	}
+	if sqrtRatioHigh.Cmp(sqrtPriceX96) <= 0 {
+		return tickHigh, nil
+	}
+	return tickLow, nil
+}
+
+// TickToPrice returns the price of token0 expressed in token1 at the given
+// tick, adjusted for the token decimals.
+func TickToPrice(tick int, token0Decimals, token1Decimals uint8) (*big.Rat, error) {
+	sqrtPriceX96, err := GetSqrtRatioAtTick(tick)
+	if err != nil {
+		return nil, err
+	}
+	return SqrtPriceX96ToPrice(sqrtPriceX96, token0Decimals, token1Decimals), nil
+}
+
+// PriceToTick returns the greatest tick whose price is lower than or equal
+// to the given price of token0 expressed in token1.
+func PriceToTick(price *big.Rat, token0Decimals, token1Decimals uint8) (int, error) {
+	return GetTickAtSqrtRatio(PriceToSqrtPriceX96(price, token0Decimals, token1Decimals))
+}
+
+// feeTickSpacing maps the fee tiers enabled on the Uniswap V3 factory to
+// their tick spacing. The 2500 tier is used by PancakeSwap V3 instead of
+// the 3000 one.
+var feeTickSpacing = map[uint32]int{
+	100:   1,
+	500:   10,
+	2500:  50,
+	3000:  60,
+	10000: 200,
+}
+
+// TickSpacing returns the tick spacing of pools with the given fee tier.
+// It returns false if the fee tier is unknown.
+func TickSpacing(fee uint32) (int, bool) {
+	spacing, ok := feeTickSpacing[fee]
+	return spacing, ok
+}
+
+// NearestUsableTick rounds the tick to the nearest multiple of the tick
+// spacing that lies within the [MinTick, MaxTick] range.
+func NearestUsableTick(tick, spacing int) int {
+	rounded := floorDiv(tick+spacing/2, spacing) * spacing
+	switch {
+	case rounded < MinTick:
+		return rounded + spacing
+	case rounded > MaxTick:
+		return rounded - spacing
+	}
+	return rounded
+}
+
+// floorDiv returns x / y rounded towards negative infinity.
+func floorDiv(x, y int) int {
+	q := x / y
+	if (x%y != 0) && ((x < 0) != (y < 0)) {
+		q--
+	}
+	return q
+}
+
+func mustHex(s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("uniswapv3: invalid hex constant " + s)
+	}
+	return x
+}
+
+func mustDec(s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("uniswapv3: invalid decimal constant " + s)
+	}
+	return x
+}
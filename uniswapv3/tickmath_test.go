@@ -0,0 +1,189 @@
+package uniswapv3
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// The expected values are taken from the TickMath tests of the Uniswap V3
+// core repository.
+func TestGetSqrtRatioAtTick(t *testing.T) {
+	tests := []struct {
+		tick  int
+		ratio string
+	}{
+		{tick: MinTick, ratio: "4295128739"},
+		{tick: MinTick + 1, ratio: "4295343490"},
+		{tick: MaxTick - 1, ratio: "1461373636630004318706518188784493106690254656249"},
+		{tick: MaxTick, ratio: "1461446703485210103287273052203988822378723970342"},
+		{tick: 50, ratio: "79426470787362580746886972461"},
+		{tick: 0, ratio: "79228162514264337593543950336"},
+	}
+	for _, tt := range tests {
+		ratio, err := GetSqrtRatioAtTick(tt.tick)
+		if err != nil {
+			t.Fatalf("GetSqrtRatioAtTick(%d): %v", tt.tick, err)
+		}
+		if ratio.String() != tt.ratio {
+			t.Errorf("GetSqrtRatioAtTick(%d) = %s, expected %s", tt.tick, ratio, tt.ratio)
+		}
+	}
+	if ratio, _ := GetSqrtRatioAtTick(MinTick); ratio.Cmp(MinSqrtRatio) != 0 {
+		t.Errorf("GetSqrtRatioAtTick(MinTick) = %s, expected MinSqrtRatio", ratio)
+	}
+	if ratio, _ := GetSqrtRatioAtTick(MaxTick); ratio.Cmp(MaxSqrtRatio) != 0 {
+		t.Errorf("GetSqrtRatioAtTick(MaxTick) = %s, expected MaxSqrtRatio", ratio)
+	}
+}
+
+func TestGetSqrtRatioAtTickOutOfRange(t *testing.T) {
+	for _, tick := range []int{MinTick - 1, MaxTick + 1} {
+		if _, err := GetSqrtRatioAtTick(tick); !errors.Is(err, ErrTickOutOfRange) {
+			t.Errorf("GetSqrtRatioAtTick(%d): expected ErrTickOutOfRange, got %v", tick, err)
+		}
+	}
+}
+
+// TestGetSqrtRatioAtTickPrecision compares the results with sqrt(1.0001^tick)
+// computed using high precision floating point numbers.
+func TestGetSqrtRatioAtTickPrecision(t *testing.T) {
+	const prec = 512
+	for _, tick := range []int{MinTick, -500000, -100000, -1000, -50, -1, 1, 50, 1000, 100000, 500000, MaxTick} {
+		ratio, err := GetSqrtRatioAtTick(tick)
+		if err != nil {
+			t.Fatalf("GetSqrtRatioAtTick(%d): %v", tick, err)
+		}
+		abs := tick
+		if abs < 0 {
+			abs = -abs
+		}
+		base := new(big.Float).SetPrec(prec).Quo(
+			new(big.Float).SetPrec(prec).SetInt64(10001),
+			new(big.Float).SetPrec(prec).SetInt64(10000),
+		)
+		pow := new(big.Float).SetPrec(prec).SetInt64(1)
+		for i := 0; i < abs; i++ {
+			pow.Mul(pow, base)
+		}
+		if tick < 0 {
+			pow.Quo(new(big.Float).SetPrec(prec).SetInt64(1), pow)
+		}
+		exact := new(big.Float).SetPrec(prec).Sqrt(pow)
+		exact.Mul(exact, new(big.Float).SetPrec(prec).SetInt(Q96))
+
+		// The library works with 128 bit fixed point numbers, so the
+		// precision drops for ticks far from zero, where the result is the
+		// inverse of a small number. It stays well below one hundredth of
+		// a basis point.
+		diff := new(big.Float).SetPrec(prec).SetInt(ratio)
+		diff.Sub(diff, exact)
+		if diff.Sign() < 0 {
+			diff.Neg(diff)
+		}
+		tolerance := new(big.Float).SetPrec(prec).Mul(exact, big.NewFloat(1e-18))
+		tolerance.Add(tolerance, big.NewFloat(1))
+		if diff.Cmp(tolerance) > 0 {
+			t.Errorf("GetSqrtRatioAtTick(%d) = %s, expected %s", tick, ratio, exact.Text('f', 0))
+		}
+	}
+}
+
+func TestGetTickAtSqrtRatio(t *testing.T) {
+	for _, tick := range []int{MinTick + 1, -887000, -100000, -1001, -50, -1, 0, 1, 50, 1001, 100000, 887000, MaxTick - 1} {
+		ratio, err := GetSqrtRatioAtTick(tick)
+		if err != nil {
+			t.Fatalf("GetSqrtRatioAtTick(%d): %v", tick, err)
+		}
+		if got, err := GetTickAtSqrtRatio(ratio); err != nil || got != tick {
+			t.Errorf("GetTickAtSqrtRatio(ratio at %d) = %d, %v, expected %d", tick, got, err, tick)
+		}
+		below := new(big.Int).Sub(ratio, big.NewInt(1))
+		if got, err := GetTickAtSqrtRatio(below); err != nil || got != tick-1 {
+			t.Errorf("GetTickAtSqrtRatio(ratio at %d - 1) = %d, %v, expected %d", tick, got, err, tick-1)
+		}
+		above := new(big.Int).Add(ratio, big.NewInt(1))
+		if got, err := GetTickAtSqrtRatio(above); err != nil || got != tick {
+			t.Errorf("GetTickAtSqrtRatio(ratio at %d + 1) = %d, %v, expected %d", tick, got, err, tick)
+		}
+	}
+
+	// Edges of the sqrt ratio range.
+	if got, err := GetTickAtSqrtRatio(MinSqrtRatio); err != nil || got != MinTick {
+		t.Errorf("GetTickAtSqrtRatio(MinSqrtRatio) = %d, %v, expected %d", got, err, MinTick)
+	}
+	maxRatio := new(big.Int).Sub(MaxSqrtRatio, big.NewInt(1))
+	if got, err := GetTickAtSqrtRatio(maxRatio); err != nil || got != MaxTick-1 {
+		t.Errorf("GetTickAtSqrtRatio(MaxSqrtRatio - 1) = %d, %v, expected %d", got, err, MaxTick-1)
+	}
+}
+
+func TestGetTickAtSqrtRatioOutOfRange(t *testing.T) {
+	for _, ratio := range []*big.Int{
+		new(big.Int).Sub(MinSqrtRatio, big.NewInt(1)),
+		MaxSqrtRatio,
+		new(big.Int).Add(MaxSqrtRatio, big.NewInt(1)),
+	} {
+		if _, err := GetTickAtSqrtRatio(ratio); !errors.Is(err, ErrSqrtRatioOutOfRange) {
+			t.Errorf("GetTickAtSqrtRatio(%s): expected ErrSqrtRatioOutOfRange, got %v", ratio, err)
+		}
+	}
+}
+
+func TestPriceToTick(t *testing.T) {
+	for _, tick := range []int{-200000, -1, 0, 1, 200000} {
+		price, err := TickToPrice(tick, 18, 6)
+		if err != nil {
+			t.Fatalf("TickToPrice(%d): %v", tick, err)
+		}
+		if got, err := PriceToTick(price, 18, 6); err != nil || got != tick {
+			t.Errorf("PriceToTick(TickToPrice(%d)) = %d, %v", tick, got, err)
+		}
+	}
+}
+
+func TestNearestUsableTick(t *testing.T) {
+	tests := []struct {
+		tick, spacing, expected int
+	}{
+		{tick: MinTick, spacing: 1, expected: MinTick},
+		{tick: MaxTick, spacing: 1, expected: MaxTick},
+		{tick: MinTick, spacing: 60, expected: -887220},
+		{tick: MaxTick, spacing: 60, expected: 887220},
+		{tick: MinTick, spacing: 200, expected: -887200},
+		{tick: MaxTick, spacing: 200, expected: 887200},
+		{tick: 5, spacing: 10, expected: 10},
+		{tick: 4, spacing: 10, expected: 0},
+		{tick: -5, spacing: 10, expected: 0},
+		{tick: -6, spacing: 10, expected: -10},
+		{tick: -121, spacing: 60, expected: -120},
+		{tick: 89, spacing: 60, expected: 60},
+		{tick: 90, spacing: 60, expected: 120},
+	}
+	for _, tt := range tests {
+		if got := NearestUsableTick(tt.tick, tt.spacing); got != tt.expected {
+			t.Errorf("NearestUsableTick(%d, %d) = %d, expected %d", tt.tick, tt.spacing, got, tt.expected)
+		}
+	}
+}
+
+func TestTickSpacing(t *testing.T) {
+	tests := []struct {
+		fee     uint32
+		spacing int
+		ok      bool
+	}{
+		{fee: 100, spacing: 1, ok: true},
+		{fee: 500, spacing: 10, ok: true},
+		{fee: 2500, spacing: 50, ok: true},
+		{fee: 3000, spacing: 60, ok: true},
+		{fee: 10000, spacing: 200, ok: true},
+		{fee: 1234, ok: false},
+	}
+	for _, tt := range tests {
+		spacing, ok := TickSpacing(tt.fee)
+		if spacing != tt.spacing || ok != tt.ok {
+			t.Errorf("TickSpacing(%d) = %d, %t, expected %d, %t", tt.fee, spacing, ok, tt.spacing, tt.ok)
+		}
+	}
+}